@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
+// serveAdmin starts the admin listener in the background and returns the server to
+// close on exit. Failing to listen is returned, so a taken port stops the start up.
+func serveAdmin(conf config.Admin, health *internal.Health) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", health.HandleHealthz)
+	mux.HandleFunc("/readyz", health.HandleReadyz)
+
+	ln, err := net.Listen("tcp", conf.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("admin listener failed", "err", err, "op", "serveAdmin")
+		}
+	}()
+
+	return srv, nil
+}
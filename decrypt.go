@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/emilekm/artifacts-mover/internal"
+)
+
+// runDecrypt restores artifacts uploaded with encryption:
+//
+//	artifacts-mover decrypt -identity key.txt [-out dir] file.bf2demo.gz.age...
+func runDecrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	identityPath := fs.String("identity", "", "path to the age identity file or SSH private key")
+	outDir := fs.String("out", "", "directory to restore the files to, next to the encrypted ones by default")
+	fs.Parse(args)
+
+	if *identityPath == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("an identity and at least one file are required")
+	}
+
+	identities, err := internal.ParseIdentities(*identityPath)
+	if err != nil {
+		return err
+	}
+
+	for _, src := range fs.Args() {
+		dir := *outDir
+		if dir == "" {
+			dir = filepath.Dir(src)
+		}
+
+		dst, err := internal.RestoreFile(src, dir, identities)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(dst)
+	}
+
+	return nil
+}
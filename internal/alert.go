@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
+//go:generate go run go.uber.org/mock/mockgen -source=./alert.go -destination=./alert_mock.go -package=internal Alerter
+
+const alertTimeout = time.Minute
+
+type AlertKind string
+
+const (
+	AlertUploadFailed AlertKind = "uploadFailed"
+	AlertRoundTimeout AlertKind = "roundTimeout"
+	AlertBacklog      AlertKind = "backlog"
+	// AlertInvalidArtifacts reports truncated or corrupt artifacts found by validation.
+	AlertInvalidArtifacts AlertKind = "invalidArtifacts"
+)
+
+// Alert describes an operational problem meant for server admins rather than players.
+type Alert struct {
+	Kind AlertKind
+	// Round is set for AlertUploadFailed, AlertRoundTimeout and AlertInvalidArtifacts.
+	Round Round
+	// Err is set for AlertUploadFailed.
+	Err error
+	// MissingTypes is set for AlertRoundTimeout.
+	MissingTypes []config.ArtifactType
+	// Backlog is the number of files in the failed upload directory, set for AlertBacklog.
+	Backlog int
+}
+
+type Alerter interface {
+	Alert(context.Context, Alert) error
+}
+
+type HandlerOption func(*Handler)
+
+// WithAlerters sends operational alerts to the given alerters. The backlog alert is
+// raised once the failed upload directory holds more than backlogThreshold files,
+// a threshold of 0 disables it.
+func WithAlerters(backlogThreshold int, alerters ...Alerter) HandlerOption {
+	return func(h *Handler) {
+		h.alerters = alerters
+		h.backlogThreshold = backlogThreshold
+	}
+}
+
+func (h *Handler) alert(alert Alert) {
+	for _, alerter := range h.alerters {
+		go func() {
+			ctx, cancel := context.WithTimeout(h.ctx, alertTimeout)
+			defer cancel()
+
+			if err := alerter.Alert(ctx, alert); err != nil {
+				slog.Error("failed to send alert", "err", err, "kind", alert.Kind, "op", "Handler.alert")
+			}
+		}()
+	}
+}
+
+// checkBacklog updates the failed upload backlog metric and raises the backlog alert
+// once the backlog is over the threshold.
+func (h *Handler) checkBacklog() {
+	backlog, err := h.backlog()
+	if err != nil {
+		slog.Error("failed to list failed uploads", "err", err, "op", "Handler.checkBacklog")
+		return
+	}
+
+	metrics.FailedBacklog.WithLabelValues(h.server).Set(float64(backlog))
+
+	if h.backlogThreshold <= 0 || len(h.alerters) == 0 {
+		return
+	}
+
+	h.backlogMu.Lock()
+	defer h.backlogMu.Unlock()
+
+	if backlog <= h.backlogThreshold {
+		h.backlogAlerted = false
+		return
+	}
+
+	if h.backlogAlerted {
+		return
+	}
+
+	h.backlogAlerted = true
+	h.alert(Alert{Kind: AlertBacklog, Backlog: backlog})
+}
+
+// backlog returns the number of files in the failed upload directory.
+func (h *Handler) backlog() (int, error) {
+	backlog := 0
+	for _, typ := range h.locToTyp {
+		files, err := filepath.Glob(filepath.Join(h.failedUploadPath, typ.String(), "*"))
+		if err != nil {
+			return 0, err
+		}
+		backlog += len(files)
+	}
+
+	return backlog, nil
+}
+
+func (h *Handler) missingTypesLocked() []config.ArtifactType {
+	missing := make([]config.ArtifactType, 0)
+	for typ := range h.artifactsConfig {
+		if _, ok := h.currentRound[typ]; !ok {
+			missing = append(missing, typ)
+		}
+	}
+
+	slices.Sort(missing)
+
+	return missing
+}
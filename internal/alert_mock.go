@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./alert.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./alert.go -destination=./alert_mock.go -package=internal Alerter
+//
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAlerter is a mock of Alerter interface.
+type MockAlerter struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlerterMockRecorder
+	isgomock struct{}
+}
+
+// MockAlerterMockRecorder is the mock recorder for MockAlerter.
+type MockAlerterMockRecorder struct {
+	mock *MockAlerter
+}
+
+// NewMockAlerter creates a new mock instance.
+func NewMockAlerter(ctrl *gomock.Controller) *MockAlerter {
+	mock := &MockAlerter{ctrl: ctrl}
+	mock.recorder = &MockAlerterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlerter) EXPECT() *MockAlerterMockRecorder {
+	return m.recorder
+}
+
+// Alert mocks base method.
+func (m *MockAlerter) Alert(arg0 context.Context, arg1 Alert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Alert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Alert indicates an expected call of Alert.
+func (mr *MockAlerterMockRecorder) Alert(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Alert", reflect.TypeOf((*MockAlerter)(nil).Alert), arg0, arg1)
+}
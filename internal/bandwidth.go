@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+const maxBandwidthBurst = 64 << 10
+
+// BandwidthLimiter caps the rate of upload streams. A limiter with a parent also
+// waits for the parent, so a server limit can be combined with a global one.
+// A nil limiter does not limit.
+type BandwidthLimiter struct {
+	limiter *rate.Limiter
+	parent  *BandwidthLimiter
+}
+
+// NewBandwidthLimiter limits streams to bytesPerSecond within parent, a non-positive
+// rate only applies the parent limit.
+func NewBandwidthLimiter(bytesPerSecond int64, parent *BandwidthLimiter) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return parent
+	}
+
+	return &BandwidthLimiter{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, maxBandwidthBurst))),
+		parent:  parent,
+	}
+}
+
+// Reader returns r limited to the rate of l. Reads waiting for the limiter fail
+// with the error of ctx once it is done.
+func (l *BandwidthLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+
+	return &limitedReader{ctx: ctx, r: r, limiter: l}
+}
+
+// burst is the largest read all limiters in the chain allow at once.
+func (l *BandwidthLimiter) burst() int {
+	burst := maxBandwidthBurst
+	for cur := l; cur != nil; cur = cur.parent {
+		burst = min(burst, cur.limiter.Burst())
+	}
+
+	return burst
+}
+
+func (l *BandwidthLimiter) wait(ctx context.Context, n int) error {
+	for cur := l; cur != nil; cur = cur.parent {
+		if err := cur.limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	require.Nil(t, NewBandwidthLimiter(0, nil))
+
+	content := bytes.Repeat([]byte{1}, 6<<10)
+
+	global := NewBandwidthLimiter(4<<10, nil)
+
+	for name, limiter := range map[string]*BandwidthLimiter{
+		"own limit":     NewBandwidthLimiter(4<<10, nil),
+		"parent limit":  NewBandwidthLimiter(0, global),
+		"unlimited own": NewBandwidthLimiter(1<<30, NewBandwidthLimiter(4<<10, nil)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+
+			read, err := io.ReadAll(limiter.Reader(context.Background(), bytes.NewReader(content)))
+			require.NoError(t, err)
+			require.Equal(t, content, read)
+
+			// The first 4 KiB are the burst, the remaining 2 KiB take half a second.
+			require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+		})
+	}
+}
+
+func TestBandwidthLimiterCancel(t *testing.T) {
+	limiter := NewBandwidthLimiter(1<<10, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Reads stop waiting for the limiter once ctx is done.
+	_, err := io.ReadAll(limiter.Reader(ctx, bytes.NewReader(bytes.Repeat([]byte{1}, 4<<10))))
+	require.ErrorIs(t, err, context.Canceled)
+}
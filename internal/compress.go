@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+// openArtifact opens the file at path as it is uploaded to loc: compressed, then encrypted.
+// Both are streamed, nothing is written to disk.
+func openArtifact(path string, loc config.Location) (io.ReadCloser, error) {
+	var recipients []age.Recipient
+	if loc.Encrypt != nil {
+		var err error
+		recipients, err = parseRecipients(loc.Encrypt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.Raw() {
+		return file, nil
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer file.Close()
+		pw.CloseWithError(compressEncrypt(pw, file, loc.Compress, recipients))
+	}()
+
+	return pr, nil
+}
+
+func compress(w io.Writer, r io.Reader, c config.Compression) error {
+	var cw io.WriteCloser
+
+	switch c {
+	case config.CompressionGzip:
+		cw = gzip.NewWriter(w)
+	case config.CompressionZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		cw = zw
+	default:
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	if _, err := io.Copy(cw, r); err != nil {
+		cw.Close()
+		return err
+	}
+
+	return cw.Close()
+}
+
+// decompress reverses compress.
+func decompress(r io.Reader, c config.Compression) (io.ReadCloser, error) {
+	switch c {
+	case config.CompressionGzip:
+		return gzip.NewReader(r)
+	case config.CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenArtifact(t *testing.T) {
+	content := bytes.Repeat([]byte("artifact content "), 1024)
+	path := filepath.Join(t.TempDir(), "round.bf2demo")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	decompress := map[config.Compression]func(io.Reader) (io.Reader, error){
+		config.CompressionNone: func(r io.Reader) (io.Reader, error) { return r, nil },
+		config.CompressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		config.CompressionZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for c, dec := range decompress {
+		name := string(c)
+		if name == "" {
+			name = "none"
+		}
+		t.Run(name, func(t *testing.T) {
+			r, err := openArtifact(path, config.Location{Compress: c})
+			require.NoError(t, err)
+			defer r.Close()
+
+			dr, err := dec(r)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(dr)
+			require.NoError(t, err)
+			require.Equal(t, content, got)
+		})
+	}
+}
@@ -10,6 +10,8 @@ const (
 	ArtifactTypeBF2Demo ArtifactType = iota // bf2demo
 	ArtifactTypePRDemo                      // prdemo
 	ArtifactTypeSummary                     // summary
+	// ArtifactTypeManifest is the checksum manifest generated for each round, it is not watched.
+	ArtifactTypeManifest // manifest
 )
 
 func (i ArtifactType) MarshalText() ([]byte, error) {
@@ -11,11 +11,12 @@ func _() {
 	_ = x[ArtifactTypeBF2Demo-0]
 	_ = x[ArtifactTypePRDemo-1]
 	_ = x[ArtifactTypeSummary-2]
+	_ = x[ArtifactTypeManifest-3]
 }
 
-const _ArtifactType_name = "bf2demoprdemosummary"
+const _ArtifactType_name = "bf2demoprdemosummarymanifest"
 
-var _ArtifactType_index = [...]uint8{0, 7, 13, 20}
+var _ArtifactType_index = [...]uint8{0, 7, 13, 20, 28}
 
 func (i ArtifactType) String() string {
 	if i < 0 || i >= ArtifactType(len(_ArtifactType_index)-1) {
@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// Extension is appended to the name of compressed artifacts on the remote.
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+func (c *Compression) UnmarshalText(text []byte) error {
+	switch Compression(text) {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		*c = Compression(text)
+	default:
+		return fmt.Errorf("unknown compression %s", string(text))
+	}
+
+	return nil
+}
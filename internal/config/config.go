@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/goccy/go-yaml"
@@ -17,51 +18,282 @@ type SCPConfig struct {
 	Username       string `yaml:"username"`
 	PrivateKeyFile string `yaml:"privateKeyFile"`
 	BasePath       string `yaml:"basePath"`
+	// Verify compares the SHA-256 of each uploaded file, computed with sha256sum on the remote, with the local one.
+	Verify    bool      `yaml:"verify,omitempty"`
+	Overwrite Overwrite `yaml:"overwrite,omitempty"`
 }
 
 type HTTPSAuth struct {
-	Basic   *BasicAuth        `yaml:"basic,omitempty"`
+	Basic *BasicAuth `yaml:"basic,omitempty"`
+	// Bearer is sent as "Authorization: Bearer <token>", e.g. a Nextcloud app password.
+	Bearer  string            `yaml:"bearer,omitempty"`
 	Headers map[string]string `yaml:"header,omitempty"`
 }
 
+// HTTPSTLS configures the TLS connection to the upload server.
+type HTTPSTLS struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones.
+	CAFile string `yaml:"caFile,omitempty"`
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile   string `yaml:"certFile,omitempty"`
+	KeyFile    string `yaml:"keyFile,omitempty"`
+	ServerName string `yaml:"serverName,omitempty"`
+}
+
+// HTTPSLinks tells where the upload response holds the download URL, either
+// JSONPath or Location is expected.
+type HTTPSLinks struct {
+	// JSONPath is the dot separated path of the URL in the JSON response body, e.g. data.url.
+	JSONPath string `yaml:"jsonPath,omitempty"`
+	// Location uses the Location header of the response.
+	Location bool `yaml:"location,omitempty"`
+}
+
+// HTTPClient configures the connection to an HTTP upload server.
+type HTTPClient struct {
+	TLS HTTPSTLS `yaml:"tls,omitempty"`
+	// ConnectTimeout limits establishing the connection, ResponseTimeout waiting for
+	// the response headers once the request is sent and Timeout the whole request.
+	ConnectTimeout  time.Duration `yaml:"connectTimeout,omitempty"`
+	ResponseTimeout time.Duration `yaml:"responseTimeout,omitempty"`
+	Timeout         time.Duration `yaml:"timeout,omitempty"`
+	// Proxy is the URL of the proxy to use, "none" disables proxies. By default
+	// the HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy        string `yaml:"proxy,omitempty"`
+	DisableHTTP2 bool   `yaml:"disableHTTP2,omitempty"`
+}
+
 type HTTPSConfig struct {
-	URL  string    `yaml:"url"`
-	Auth HTTPSAuth `yaml:"auth"`
+	URL        string    `yaml:"url"`
+	Auth       HTTPSAuth `yaml:"auth"`
+	HTTPClient `yaml:",inline"`
+	// Links reads the download URL of each file from the upload response.
+	Links *HTTPSLinks `yaml:"links,omitempty"`
+	// Verify compares the SHA-256 returned by the server in ChecksumHeader with the one of the uploaded file.
+	Verify         bool      `yaml:"verify,omitempty"`
+	ChecksumHeader string    `yaml:"checksumHeader,omitempty"`
+	Overwrite      Overwrite `yaml:"overwrite,omitempty"`
+	// TwoPhase uploads files under a partial name and publishes them with a MOVE request once complete.
+	TwoPhase  bool       `yaml:"twoPhase,omitempty"`
+	Resumable *Resumable `yaml:"resumable,omitempty"`
+}
+
+// Resumable switches the HTTPS uploader to the tus resumable upload protocol.
+type Resumable struct {
+	// ChunkSize is the size in bytes of each PATCH request, 8 MiB by default.
+	ChunkSize int64 `yaml:"chunkSize,omitempty"`
+	// Retries is the number of times an interrupted upload is resumed before the round fails.
+	Retries int `yaml:"retries,omitempty"`
+	// RetryDelay is the wait before resuming, 5s by default and at most a minute.
+	RetryDelay time.Duration `yaml:"retryDelay,omitempty"`
+}
+
+// WebDAVConfig uploads artifacts with PUT requests to a WebDAV server, e.g. Nextcloud.
+type WebDAVConfig struct {
+	// URL is the collection the upload paths are relative to.
+	URL        string    `yaml:"url"`
+	Auth       HTTPSAuth `yaml:"auth"`
+	HTTPClient `yaml:",inline"`
+	// Retries is the number of times a request failing with a network error, 429 or 5xx is repeated.
+	Retries    int           `yaml:"retries,omitempty"`
+	RetryDelay time.Duration `yaml:"retryDelay,omitempty"`
+	Overwrite  Overwrite     `yaml:"overwrite,omitempty"`
+}
+
+// FTPConfig uploads artifacts to an FTP server, e.g. the file manager of a game hosting panel.
+// Transfers always use passive mode.
+type FTPConfig struct {
+	// Address is host:port, the port defaults to 21, or 990 with implicit TLS.
+	Address  string  `yaml:"address"`
+	Username string  `yaml:"username"`
+	Password string  `yaml:"password"`
+	BasePath string  `yaml:"basePath"`
+	TLS      *FTPTLS `yaml:"tls,omitempty"`
+	// DisableEPSV opens data connections with PASV only, for servers answering EPSV wrongly.
+	DisableEPSV bool `yaml:"disableEPSV,omitempty"`
+	// Timeout limits connecting, 30s by default.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Retries is the number of times an interrupted upload is resumed before the round fails.
+	Retries    int           `yaml:"retries,omitempty"`
+	RetryDelay time.Duration `yaml:"retryDelay,omitempty"`
+	Overwrite  Overwrite     `yaml:"overwrite,omitempty"`
+}
+
+// FTPTLS enables FTPS, explicit (AUTH TLS) by default.
+type FTPTLS struct {
+	// Implicit starts TLS as soon as the connection is open, usually on port 990.
+	Implicit bool `yaml:"implicit,omitempty"`
+	HTTPSTLS `yaml:",inline"`
+}
+
+// LocalConfig copies artifacts to a directory on the same host, e.g. the web root or a mounted share.
+type LocalConfig struct {
+	BasePath string `yaml:"basePath"`
+	// FileMode of the uploaded files, the mode of the artifact is kept when empty.
+	FileMode FileMode `yaml:"fileMode,omitempty"`
+	// Owner and Group of the uploaded files, names or numeric ids.
+	Owner     string    `yaml:"owner,omitempty"`
+	Group     string    `yaml:"group,omitempty"`
+	Overwrite Overwrite `yaml:"overwrite,omitempty"`
 }
 
 // TODO: Implement SFTP
 // type SFTPConfig struct {
 // }
 
+// Encryption encrypts artifacts with age before they are uploaded.
+type Encryption struct {
+	// Recipients are age public keys (age1...) or SSH public keys (ssh-ed25519, ssh-rsa).
+	Recipients []string `yaml:"recipients"`
+}
+
 type UploadConfig struct {
-	SCP   *SCPConfig   `yaml:"scp,omitempty"`
-	HTTPS *HTTPSConfig `yaml:"https,omitempty"`
+	SCP    *SCPConfig    `yaml:"scp,omitempty"`
+	HTTPS  *HTTPSConfig  `yaml:"https,omitempty"`
+	WebDAV *WebDAVConfig `yaml:"webdav,omitempty"`
+	FTP    *FTPConfig    `yaml:"ftp,omitempty"`
+	Local  *LocalConfig  `yaml:"local,omitempty"`
 	// SFTP  *SFTPConfig  `yaml:"sftp,omitempty"`
+	// Encrypt applies to every artifact type of the server.
+	Encrypt *Encryption `yaml:"encrypt,omitempty"`
+}
+
+// Schedule restricts the uploads of an artifact type to time windows.
+type Schedule struct {
+	// Hours are local time windows such as "02:00-08:00", a window may span midnight.
+	Hours []string `yaml:"hours"`
+	// BetweenRounds holds uploads back while the next round is played, they start once it ends.
+	BetweenRounds bool `yaml:"betweenRounds,omitempty"`
 }
 
 type Location struct {
-	Location   string  `yaml:"location"`
-	UploadPath string  `yaml:"uploadPath"`
-	MovePath   *string `yaml:"movePath,omitempty"`
+	Location   string      `yaml:"location"`
+	UploadPath string      `yaml:"uploadPath"`
+	MovePath   *string     `yaml:"movePath,omitempty"`
+	Compress   Compression `yaml:"compress,omitempty"`
+	Schedule   *Schedule   `yaml:"schedule,omitempty"`
+	// Encrypt is taken from the upload config of the server.
+	Encrypt *Encryption `yaml:"-"`
+}
+
+// Raw reports whether artifacts are uploaded as they are on disk.
+func (l Location) Raw() bool {
+	return l.Compress == CompressionNone && l.Encrypt == nil
 }
 
 type ArtifactsConfig map[ArtifactType]Location
 
+// RemoteName is the file name of the artifact at path once uploaded.
+func (c ArtifactsConfig) RemoteName(typ ArtifactType, path string) string {
+	name := filepath.Base(path) + c[typ].Compress.Extension()
+	if c[typ].Encrypt != nil {
+		name += ".age"
+	}
+
+	return name
+}
+
+type DiscordButtons struct {
+	BF2Demo string `yaml:"bf2demo,omitempty"`
+	PRDemo  string `yaml:"prdemo,omitempty"`
+	Tracker string `yaml:"tracker,omitempty"`
+}
+
+// DiscordTemplates holds text/template overrides of the round post.
+type DiscordTemplates struct {
+	Title       string         `yaml:"title,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Footer      string         `yaml:"footer,omitempty"`
+	Color       string         `yaml:"color,omitempty"`
+	Buttons     DiscordButtons `yaml:"buttons,omitempty"`
+}
+
+// DiscordFonts overrides the embedded fonts used in summary images with TTF files,
+// for locales whose scripts the embedded faces do not cover.
+type DiscordFonts struct {
+	Regular      string `yaml:"regular,omitempty"`
+	Bold         string `yaml:"bold,omitempty"`
+	MediumItalic string `yaml:"mediumItalic,omitempty"`
+}
+
 type Discord struct {
 	ChannelID string            `yaml:"channelID"`
 	URLS      map[string]string `yaml:"urls"`
+	// Placeholder posts a message as soon as a round ends and edits it once the upload finishes.
+	Placeholder bool             `yaml:"placeholder,omitempty"`
+	Templates   DiscordTemplates `yaml:"templates,omitempty"`
+	Locale      string           `yaml:"locale,omitempty"`
+	Fonts       DiscordFonts     `yaml:"fonts,omitempty"`
+	// ImageLayout is either compact (default) or extended.
+	ImageLayout string `yaml:"imageLayout,omitempty"`
+	// TopPlayers is the number of players per team shown by the extended layout.
+	TopPlayers int `yaml:"topPlayers,omitempty"`
+	// TicketChart attaches a chart of both teams' tickets over time, drawn from the PR demo.
+	TicketChart bool `yaml:"ticketChart,omitempty"`
+}
+
+type Webhook struct {
+	URL        string            `yaml:"url"`
+	Secret     string            `yaml:"secret,omitempty"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	URLS       map[string]string `yaml:"urls"`
+	Timeout    time.Duration     `yaml:"timeout,omitempty"`
+	Retries    int               `yaml:"retries,omitempty"`
+	RetryDelay time.Duration     `yaml:"retryDelay,omitempty"`
+}
+
+type Notifier struct {
+	Discord    *Discord      `yaml:"discord,omitempty"`
+	Webhook    *Webhook      `yaml:"webhook,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
+	Retries    int           `yaml:"retries,omitempty"`
+	RetryDelay time.Duration `yaml:"retryDelay,omitempty"`
+}
+
+type Alerts struct {
+	Discord          *Discord `yaml:"discord,omitempty"`
+	Webhook          *Webhook `yaml:"webhook,omitempty"`
+	BacklogThreshold int      `yaml:"backlogThreshold,omitempty"`
+}
+
+// Manifest uploads a JSON manifest with the size and SHA-256 of every artifact of a round.
+type Manifest struct {
+	UploadPath string `yaml:"uploadPath"`
 }
 
 type Server struct {
-	Upload       UploadConfig    `yaml:"upload"`
-	Artifacts    ArtifactsConfig `yaml:"types"`
-	Discord      Discord         `yaml:"discord,omitempty"`
-	RoundTimeout time.Duration   `yaml:"roundTimeout,omitempty"`
+	Upload    UploadConfig    `yaml:"upload"`
+	Artifacts ArtifactsConfig `yaml:"types"`
+	Discord   Discord         `yaml:"discord,omitempty"`
+	Webhook   *Webhook        `yaml:"webhook,omitempty"`
+	Notifiers []Notifier      `yaml:"notifiers,omitempty"`
+	Alerts    *Alerts         `yaml:"alerts,omitempty"`
+	Manifest  *Manifest       `yaml:"manifest,omitempty"`
+	// Validate checks artifacts before upload and quarantines corrupt ones.
+	Validate     bool          `yaml:"validate,omitempty"`
+	RoundTimeout time.Duration `yaml:"roundTimeout,omitempty"`
+	// BandwidthLimit caps the upload rate of the server in bytes per second.
+	BandwidthLimit int64 `yaml:"bandwidthLimit,omitempty"`
+}
+
+// Admin configures the HTTP listener for operators, serving Prometheus metrics on /metrics
+// and the health checks on /healthz and /readyz.
+type Admin struct {
+	// Address is the host:port to listen on, e.g. :9090.
+	Address string `yaml:"address"`
+	// CheckInterval is how often upload destinations are checked for /readyz, 1m by default.
+	CheckInterval time.Duration `yaml:"checkInterval,omitempty"`
+	// StallTimeout is how long the file watcher loop may be busy before /healthz fails, 1h by default.
+	// The loop uploads the rounds it ends, so it has to cover the longest round upload.
+	StallTimeout time.Duration `yaml:"stallTimeout,omitempty"`
 }
 
 type Config struct {
 	FailedUploadPath string             `yaml:"failedUploadPath"`
 	Servers          map[string]*Server `yaml:"servers"`
+	// BandwidthLimit caps the upload rate of all servers together in bytes per second.
+	BandwidthLimit int64  `yaml:"bandwidthLimit,omitempty"`
+	Admin          *Admin `yaml:"admin,omitempty"`
 }
 
 func New(filename string) (*Config, error) {
@@ -76,5 +308,16 @@ func New(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	for _, server := range c.Servers {
+		if server.Upload.Encrypt == nil {
+			continue
+		}
+
+		for typ, loc := range server.Artifacts {
+			loc.Encrypt = server.Upload.Encrypt
+			server.Artifacts[typ] = loc
+		}
+	}
+
 	return &c, nil
 }
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileMode is a permission mode written in octal, e.g. "0644".
+type FileMode os.FileMode
+
+// UnmarshalYAML reads the raw value, as an unquoted 0644 would otherwise be decoded as a decimal number.
+func (m *FileMode) UnmarshalYAML(b []byte) error {
+	text := strings.Trim(strings.TrimSpace(string(b)), `"'`)
+
+	mode, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("invalid file mode %s", text)
+	}
+
+	*m = FileMode(mode)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileModeUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected FileMode
+		err      bool
+	}{
+		{name: "octal", input: `fileMode: 0644`, expected: 0o644},
+		{name: "quoted", input: `fileMode: "0644"`, expected: 0o644},
+		{name: "without leading zero", input: `fileMode: 644`, expected: 0o644},
+		{name: "not octal", input: `fileMode: 0999`, err: true},
+		{name: "symbolic", input: `fileMode: rw-r--r--`, err: true},
+		{name: "special bits", input: `fileMode: 04755`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf LocalConfig
+			err := yaml.Unmarshal([]byte(tt.input), &conf)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, conf.FileMode)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "fmt"
+
+// Overwrite decides what uploaders do with artifacts that already exist on the remote.
+type Overwrite string
+
+const (
+	// OverwriteAlways uploads every artifact, the default.
+	OverwriteAlways Overwrite = ""
+	// OverwriteNever skips artifacts that exist on the remote.
+	OverwriteNever Overwrite = "never"
+	// OverwriteIfDifferent skips artifacts that exist on the remote with the same size and checksum.
+	OverwriteIfDifferent Overwrite = "if-different"
+)
+
+func (o *Overwrite) UnmarshalText(text []byte) error {
+	switch Overwrite(text) {
+	case OverwriteAlways, OverwriteNever, OverwriteIfDifferent:
+		*o = Overwrite(text)
+	case "always":
+		*o = OverwriteAlways
+	default:
+		return fmt.Errorf("unknown overwrite policy %s", string(text))
+	}
+
+	return nil
+}
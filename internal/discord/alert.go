@@ -0,0 +1,93 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/emilekm/artifacts-mover/internal"
+)
+
+const alertColor = 0xF54242
+
+var alertTitles = map[internal.AlertKind]string{
+	internal.AlertUploadFailed:     "Upload failed",
+	internal.AlertRoundTimeout:     "Incomplete round sealed by timeout",
+	internal.AlertBacklog:          "Failed upload backlog is growing",
+	internal.AlertInvalidArtifacts: "Invalid artifacts",
+}
+
+// Alerter posts operational alerts of a single server to an admin channel.
+type Alerter struct {
+	session   discordSession
+	channelID string
+	server    string
+}
+
+func NewAlerter(session discordSession, channelID string, server string) *Alerter {
+	return &Alerter{
+		session:   instrumentedSession{session},
+		channelID: channelID,
+		server:    server,
+	}
+}
+
+func (a *Alerter) Alert(ctx context.Context, alert internal.Alert) error {
+	embed := &discordgo.MessageEmbed{
+		Title:  alertTitles[alert.Kind],
+		Type:   discordgo.EmbedTypeRich,
+		Color:  alertColor,
+		Fields: []*discordgo.MessageEmbedField{{Name: "Server", Value: a.server, Inline: true}},
+	}
+
+	if alert.Err != nil {
+		embed.Description = fmt.Sprintf("```\n%s\n```", alert.Err)
+	}
+
+	if len(alert.MissingTypes) > 0 {
+		missing := make([]string, 0, len(alert.MissingTypes))
+		for _, typ := range alert.MissingTypes {
+			missing = append(missing, typ.String())
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Missing",
+			Value:  strings.Join(missing, ", "),
+			Inline: true,
+		})
+	}
+
+	if len(alert.Round) > 0 {
+		files := make([]string, 0, len(alert.Round))
+		for _, artifact := range alert.Round {
+			file := filepath.Base(artifact.Path)
+			if artifact.Problem != "" {
+				file = fmt.Sprintf("%s (%s: %s)", file, artifact.Status, artifact.Problem)
+			}
+			files = append(files, file)
+		}
+		slices.Sort(files)
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Files",
+			Value: strings.Join(files, "\n"),
+		})
+	}
+
+	if alert.Kind == internal.AlertBacklog {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Failed uploads",
+			Value:  strconv.Itoa(alert.Backlog),
+			Inline: true,
+		})
+	}
+
+	_, err := a.session.ChannelMessageSendComplex(a.channelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}, discordgo.WithContext(ctx))
+	return err
+}
@@ -0,0 +1,144 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/emilekm/artifacts-mover/internal/summary"
+	"github.com/fogleman/gg"
+)
+
+const (
+	chartWidth  = 400
+	chartHeight = 200
+
+	chartMarginLeft   = 40
+	chartMarginRight  = 12
+	chartMarginTop    = 28
+	chartMarginBottom = 24
+
+	chartGridLines = 4
+)
+
+var (
+	team1ChartColor = [3]float64{0.96, 0.26, 0.26}
+	team2ChartColor = [3]float64{0.26, 0.52, 0.96}
+)
+
+func createTicketChart(samples []summary.TicketSample, team1Name, team2Name string, loc *locale) (io.Reader, error) {
+	img, err := renderTicketChart(samples, team1Name, team2Name, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+
+	err = png.Encode(out, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// renderTicketChart draws the tickets of both teams as step lines over the round time.
+func renderTicketChart(samples []summary.TicketSample, team1Name, team2Name string, loc *locale) (image.Image, error) {
+	dc := gg.NewContext(chartWidth, chartHeight)
+
+	dc.SetRGB(0.12, 0.12, 0.12)
+	dc.Clear()
+
+	if len(samples) == 0 {
+		return dc.Image(), nil
+	}
+
+	maxTickets := 1
+	for _, s := range samples {
+		maxTickets = max(maxTickets, s.Team1, s.Team2)
+	}
+
+	end := samples[len(samples)-1].Time
+	if end <= 0 {
+		end = 1
+	}
+
+	plotW := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotH := float64(chartHeight - chartMarginTop - chartMarginBottom)
+
+	x := func(i int) float64 {
+		return chartMarginLeft + plotW*float64(samples[i].Time)/float64(end)
+	}
+	y := func(tickets int) float64 {
+		return chartMarginTop + plotH*(1-float64(tickets)/float64(maxTickets))
+	}
+
+	if err := loc.setFont(dc, 10, fontTypeRegular); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i <= chartGridLines; i++ {
+		tickets := maxTickets * i / chartGridLines
+		gy := y(tickets)
+
+		dc.SetRGBA(1, 1, 1, 0.15)
+		dc.SetLineWidth(1)
+		dc.DrawLine(chartMarginLeft, gy, chartWidth-chartMarginRight, gy)
+		dc.Stroke()
+
+		dc.SetRGB(0.8, 0.8, 0.8)
+		dc.DrawStringAnchored(strconv.Itoa(tickets), chartMarginLeft-4, gy, 1, 0.5)
+	}
+
+	minutes := int(end.Minutes())
+	for i, m := range []int{0, minutes / 2, minutes} {
+		ax := []float64{0, 0.5, 1}[i]
+		dc.DrawStringAnchored(fmt.Sprintf(loc.text.Minutes, m), chartMarginLeft+plotW*ax, chartHeight-chartMarginBottom/2, ax, 0.5)
+	}
+
+	for _, team := range []struct {
+		color   [3]float64
+		tickets func(summary.TicketSample) int
+	}{
+		{color: team1ChartColor, tickets: func(s summary.TicketSample) int { return s.Team1 }},
+		{color: team2ChartColor, tickets: func(s summary.TicketSample) int { return s.Team2 }},
+	} {
+		dc.SetRGB(team.color[0], team.color[1], team.color[2])
+		dc.SetLineWidth(2)
+
+		dc.MoveTo(x(0), y(team.tickets(samples[0])))
+		for i := 1; i < len(samples); i++ {
+			dc.LineTo(x(i), y(team.tickets(samples[i-1])))
+			dc.LineTo(x(i), y(team.tickets(samples[i])))
+		}
+		dc.Stroke()
+	}
+
+	if err := loc.setFont(dc, 12, fontTypeBold); err != nil {
+		return nil, err
+	}
+
+	legendX := float64(chartMarginLeft)
+	for _, entry := range []struct {
+		name  string
+		color [3]float64
+	}{
+		{name: team1Name, color: team1ChartColor},
+		{name: team2Name, color: team2ChartColor},
+	} {
+		dc.SetRGB(entry.color[0], entry.color[1], entry.color[2])
+		dc.DrawRectangle(legendX, chartMarginTop/2-5, 10, 10)
+		dc.Fill()
+
+		dc.SetRGB(1, 1, 1)
+		dc.DrawStringAnchored(entry.name, legendX+14, chartMarginTop/2, 0, 0.5)
+
+		w, _ := dc.MeasureString(entry.name)
+		legendX += 14 + w + 16
+	}
+
+	return dc.Image(), nil
+}
@@ -2,67 +2,85 @@ package discord
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/emilekm/artifacts-mover/internal"
 	"github.com/emilekm/artifacts-mover/internal/config"
-	"github.com/emilekm/go-prbf2/prdemo"
+	"github.com/emilekm/artifacts-mover/internal/summary"
 )
 
 //go:generate go run ./assets/scripts/generate_assets.go
 
 type discordSession interface {
 	ChannelMessageSendComplex(channelID string, msg *discordgo.MessageSend, opts ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(msg *discordgo.MessageEdit, opts ...discordgo.RequestOption) (*discordgo.Message, error)
 }
 
 const (
 	trackerType = "tracker"
-
-	embedDescriptionFmt = `**_%s, %s_**
-
-Duration: %d minutes
-Started: <t:%d:R> | <t:%d:F>
-Ended: <t:%d:R> | <t:%d:F>`
 )
 
-type player struct {
-	Name  string
-	Score int
+type Client struct {
+	session     discordSession
+	channelID   string
+	typToURL    map[string]string
+	artifacts   config.ArtifactsConfig
+	placeholder bool
+	templates   *templates
+	locale      *locale
+	image       imageOptions
+	ticketChart bool
+
+	mu      sync.Mutex
+	pending map[string]*pendingMessage
 }
 
-type jsonSummary struct {
-	MapName  string
-	MapMode  string
-	MapLayer int
+func New(session discordSession, conf config.Discord, artifacts config.ArtifactsConfig) (*Client, error) {
+	loc, err := newLocale(conf.Locale, conf.Fonts)
+	if err != nil {
+		return nil, err
+	}
 
-	Team1Name    string
-	Team2Name    string
-	Team1Tickets int
-	Team2Tickets int
+	tmpls, err := parseTemplates(conf.Templates, loc.catalog)
+	if err != nil {
+		return nil, err
+	}
 
-	StartTime int64
-	EndTime   int64
-	Players   []player
-}
+	opts := imageOptions{
+		Layout:     conf.ImageLayout,
+		TopPlayers: conf.TopPlayers,
+	}
 
-type Client struct {
-	session   discordSession
-	channelID string
-	typToURL  map[string]string
-}
+	switch opts.Layout {
+	case "":
+		opts.Layout = layoutCompact
+	case layoutCompact, layoutExtended:
+	default:
+		return nil, fmt.Errorf("unknown image layout %s", opts.Layout)
+	}
+
+	if opts.TopPlayers <= 0 {
+		opts.TopPlayers = defaultTopPlayers
+	}
 
-func New(session discordSession, channelID string, typToURL map[string]string) (*Client, error) {
 	return &Client{
-		session:   session,
-		channelID: channelID,
-		typToURL:  typToURL,
+		session:     instrumentedSession{session},
+		channelID:   conf.ChannelID,
+		typToURL:    conf.URLS,
+		artifacts:   artifacts,
+		placeholder: conf.Placeholder,
+		templates:   tmpls,
+		locale:      loc,
+		image:       opts,
+		ticketChart: conf.TicketChart,
+		pending:     make(map[string]*pendingMessage),
 	}, nil
 }
 
@@ -71,183 +89,191 @@ func (w *Client) Send(ctx context.Context, round internal.Round) error {
 		Files: make([]*discordgo.File, 0),
 	}
 
-	row := discordgo.ActionsRow{}
+	// Corrupt artifacts were not uploaded, the round itself is kept as the placeholder key.
+	usable := round.Usable()
+
+	urls := make(map[string]string)
+	for typ, artifact := range usable {
+		filename := w.artifacts.RemoteName(typ, artifact.Path)
+		if artifact.URL != "" {
+			// The upload server may rename the file, the tracker gets the name it chose.
+			urls[typ.String()] = artifact.URL
+			filename = path.Base(artifact.URL)
+		} else {
+			urls[typ.String()] = w.typToURL[typ.String()] + "/" + filename
+		}
+		// The tracker cannot read compressed or encrypted PR demos.
+		if typ == config.ArtifactTypePRDemo && w.artifacts[typ].Raw() {
+			urls[trackerType] = w.typToURL[trackerType] + filename
+		}
+	}
 
-	tickets := struct {
-		Team1 *int16
-		Team2 *int16
-	}{}
+	roundSummary, demo, err := summary.Resolve(usable[config.ArtifactTypeSummary].Path, usable[config.ArtifactTypePRDemo].Path)
+	if err != nil {
+		return err
+	}
 
-	if prDemo, ok := round[config.ArtifactTypePRDemo]; ok {
-		team1Tickets, team2Tickets, err := extractValidEndTickets(prDemo.Path)
+	data := newTemplateData(roundSummary, w.locale.catalog, urls)
+
+	// Encrypted PR demos must not be posted in the clear.
+	if prDemo, ok := usable[config.ArtifactTypePRDemo]; ok && w.artifacts[config.ArtifactTypePRDemo].Encrypt == nil {
+		file, err := os.Open(prDemo.Path)
 		if err != nil {
-			slog.Warn("failed to extract valid end tickets from PR demo", "error", err)
+			return err
 		}
-		tickets.Team1 = &team1Tickets
-		tickets.Team2 = &team2Tickets
-	}
 
-	buttons := make([]discordgo.Button, 0)
+		defer file.Close()
 
-	for typ, artifact := range round {
-		filename := filepath.Base(artifact.Path)
-		switch typ {
-		case config.ArtifactTypeBF2Demo:
-			buttons = append(buttons, discordgo.Button{
-				Label: "Download Battle Recorder",
-				URL:   w.typToURL[typ.String()] + "/" + filename,
-				Style: discordgo.LinkButton,
-			})
-		case config.ArtifactTypePRDemo:
-			file, err := os.Open(artifact.Path)
-			if err != nil {
-				return err
-			}
-
-			defer file.Close()
-
-			msg.Files = append(msg.Files, &discordgo.File{
-				Name:   filename,
-				Reader: file,
-			})
-
-			buttons = append(buttons, discordgo.Button{
-				Label: "Download Tracker",
-				URL:   w.typToURL[typ.String()] + "/" + filename,
-				Style: discordgo.LinkButton,
-			}, discordgo.Button{
-				Label: "View Tracker",
-				URL:   w.typToURL[trackerType] + filename,
-				Style: discordgo.LinkButton,
-			})
-		case config.ArtifactTypeSummary:
-			summaryContent, err := os.ReadFile(artifact.Path)
-			if err != nil {
-				return err
-			}
-
-			var summary jsonSummary
-			if err := json.Unmarshal(summaryContent, &summary); err != nil {
-				return err
-			}
-
-			if tickets.Team1 != nil {
-				summary.Team1Tickets = int(*tickets.Team1)
-			}
-			if tickets.Team2 != nil {
-				summary.Team2Tickets = int(*tickets.Team2)
-			}
-
-			imgReader, err := createImage(&summary)
-			if err != nil {
-				return err
-			}
-
-			imageFilename := "summary.png"
-
-			msg.Files = append(msg.Files, &discordgo.File{
-				Name:   imageFilename,
-				Reader: imgReader,
-			})
-
-			timestamp, err := time.Unix(summary.EndTime, 0).MarshalText()
-			if err != nil {
-				return err
-			}
-
-			mapDetails, ok := levels[summary.MapName]
-			if !ok {
-				mapDetails = level{
-					Name: summary.MapName,
-					Size: 0,
-				}
-			}
-
-			msg.Embeds = append(msg.Embeds, &discordgo.MessageEmbed{
-				Title: fmt.Sprintf("%s (%d km)", mapDetails.Name, mapDetails.Size),
-				Type:  discordgo.EmbedTypeRich,
-				Color: gameModes[summary.MapMode].Color,
-				Description: fmt.Sprintf(
-					embedDescriptionFmt,
-					gameModes[summary.MapMode].Name,
-					layers[summary.MapLayer],
-					(summary.EndTime-summary.StartTime)/60,
-					summary.StartTime,
-					summary.StartTime,
-					summary.EndTime,
-					summary.EndTime,
-				),
-				Timestamp: string(timestamp),
-				Image: &discordgo.MessageEmbedImage{
-					URL: "attachment://" + imageFilename,
-				},
-			})
+		msg.Files = append(msg.Files, &discordgo.File{
+			Name:   filepath.Base(prDemo.Path),
+			Reader: file,
+		})
+	}
+
+	if roundSummary != nil {
+		embed, file, err := w.buildEmbed(roundSummary, data)
+		if err != nil {
+			return err
 		}
+
+		msg.Files = append(msg.Files, file)
+		msg.Embeds = append(msg.Embeds, embed)
 	}
 
-	slices.SortFunc(buttons, func(i, j discordgo.Button) int {
-		if i.Label < j.Label {
-			return 1
-		} else if i.Label > j.Label {
-			return -1
+	if w.ticketChart && demo != nil && len(demo.Tickets) > 1 && (roundSummary == nil || roundSummary.MapMode != gpmGungame) {
+		embed, file, err := w.buildTicketChart(roundSummary, demo, data)
+		if err != nil {
+			return err
 		}
 
-		return 0
-	})
+		msg.Files = append(msg.Files, file)
+		msg.Embeds = append(msg.Embeds, embed)
+	}
+
+	row := discordgo.ActionsRow{}
+
+	for _, typ := range []string{trackerType, config.ArtifactTypePRDemo.String(), config.ArtifactTypeBF2Demo.String()} {
+		url, ok := urls[typ]
+		if !ok {
+			continue
+		}
 
-	for _, button := range buttons {
-		row.Components = append(row.Components, button)
+		row.Components = append(row.Components, discordgo.Button{
+			Label: w.templates.button(typ, data),
+			URL:   url,
+			Style: discordgo.LinkButton,
+		})
 	}
 
 	msg.Components = []discordgo.MessageComponent{row}
+	msg.Content = w.incompleteNotice(round)
 
-	_, err := w.session.ChannelMessageSendComplex(w.channelID, msg, discordgo.WithContext(ctx))
-	return err
+	return w.sendOrEdit(ctx, round, msg)
 }
 
-func extractValidEndTickets(prDemoPath string) (int16, int16, error) {
-	demo, err := prdemo.NewDemoReaderFromFile(prDemoPath)
+func (w *Client) buildEmbed(s *summary.Summary, data *templateData) (*discordgo.MessageEmbed, *discordgo.File, error) {
+	imgReader, err := createImage(s, w.locale, w.image)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imageFilename := "summary.png"
+
+	timestamp, err := data.EndTime.MarshalText()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	title, err := execute(w.templates.title, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	description, err := execute(w.templates.description, data)
 	if err != nil {
-		return 0, 0, err
+		return nil, nil, err
 	}
 
-	team1, team2 := int16(0), int16(0)
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Type:        discordgo.EmbedTypeRich,
+		Color:       data.Mode.Color,
+		Description: description,
+		Timestamp:   string(timestamp),
+		Image: &discordgo.MessageEmbedImage{
+			URL: "attachment://" + imageFilename,
+		},
+	}
+
+	footer, err := execute(w.templates.footer, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+
+	color, err := execute(w.templates.color, data)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for demo.Next() {
-		msg, err := demo.GetMessage()
+	if color != "" {
+		embed.Color, err = parseColor(color)
 		if err != nil {
-			return 0, 0, err
+			return nil, nil, err
 		}
+	}
+
+	return embed, &discordgo.File{
+		Name:   imageFilename,
+		Reader: imgReader,
+	}, nil
+}
 
-		switch msg.Type {
-		case prdemo.TicketsTeam1Type:
-			var ticketMsg prdemo.Tickets
-			err = msg.Decode(&ticketMsg)
-			if err != nil {
-				continue
-			}
-
-			if ticketMsg.Tickets < 0 {
-				ticketMsg.Tickets = 0
-			}
-
-			team1 = ticketMsg.Tickets
-		case prdemo.TicketsTeam2Type:
-			var ticketMsg prdemo.Tickets
-			err = msg.Decode(&ticketMsg)
-			if err != nil {
-				continue
-			}
-
-			if ticketMsg.Tickets < 0 {
-				ticketMsg.Tickets = 0
-			}
-
-			team2 = ticketMsg.Tickets
-		case prdemo.RoundEndType:
-			return team1, team2, nil
+func (w *Client) buildTicketChart(s *summary.Summary, demo *summary.Demo, data *templateData) (*discordgo.MessageEmbed, *discordgo.File, error) {
+	team1Name, team2Name := "Team 1", "Team 2"
+	switch {
+	case s != nil:
+		team1Name, team2Name = s.Team1Name, s.Team2Name
+	case demo.Details != nil:
+		team1Name, team2Name = demo.Details.OpforTeam, demo.Details.BluforTeam
+	}
+
+	chartReader, err := createTicketChart(demo.Tickets, strings.ToUpper(team1Name), strings.ToUpper(team2Name), w.locale)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chartFilename := "tickets.png"
+
+	return &discordgo.MessageEmbed{
+		Type:  discordgo.EmbedTypeRich,
+		Color: data.Mode.Color,
+		Image: &discordgo.MessageEmbedImage{
+			URL: "attachment://" + chartFilename,
+		},
+	}, &discordgo.File{
+		Name:   chartFilename,
+		Reader: chartReader,
+	}, nil
+}
+
+// incompleteNotice lists the artifacts that failed validation, it is empty for complete rounds.
+func (w *Client) incompleteNotice(round internal.Round) string {
+	types := make([]string, 0)
+	for typ, artifact := range round {
+		if artifact.Status == internal.ArtifactTruncated || artifact.Status == internal.ArtifactCorrupt {
+			types = append(types, typ.String())
 		}
 	}
 
-	return team1, team2, nil
+	if len(types) == 0 {
+		return ""
+	}
+
+	slices.Sort(types)
+
+	return fmt.Sprintf(w.locale.text.Incomplete, strings.Join(types, ", "))
 }
@@ -0,0 +1,74 @@
+package discord
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/emilekm/artifacts-mover/internal"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendPRDemo(t *testing.T) {
+	prDemo := filepath.Join(t.TempDir(), "tracker_2024_01_01_20_00_00.PRdemo")
+	require.NoError(t, os.WriteFile(prDemo, []byte("PR demo"), 0644))
+
+	round := internal.Round{
+		config.ArtifactTypePRDemo: {Path: prDemo, Type: config.ArtifactTypePRDemo},
+	}
+
+	encryption := &config.Encryption{Recipients: []string{"age1recipient"}}
+
+	tests := []struct {
+		name     string
+		loc      config.Location
+		attached bool
+		tracker  bool
+	}{
+		{name: "raw", attached: true, tracker: true},
+		{name: "compressed", loc: config.Location{Compress: config.CompressionGzip}, attached: true},
+		{name: "encrypted", loc: config.Location{Encrypt: encryption}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &fakeSession{}
+			client, err := New(session, config.Discord{
+				ChannelID: "channel",
+				URLS: map[string]string{
+					"prdemo":  "https://example.com/prdemos",
+					"tracker": "https://example.com/tracker?demo=",
+				},
+			}, config.ArtifactsConfig{config.ArtifactTypePRDemo: tt.loc})
+			require.NoError(t, err)
+
+			require.NoError(t, client.Send(context.Background(), round))
+			require.Len(t, session.sent, 1)
+
+			msg := session.sent[0]
+
+			var files []string
+			for _, file := range msg.Files {
+				files = append(files, file.Name)
+			}
+			if tt.attached {
+				require.Equal(t, []string{"tracker_2024_01_01_20_00_00.PRdemo"}, files)
+			} else {
+				require.Empty(t, files)
+			}
+
+			var urls []string
+			for _, component := range msg.Components[0].(discordgo.ActionsRow).Components {
+				urls = append(urls, component.(discordgo.Button).URL)
+			}
+			if tt.tracker {
+				require.Contains(t, urls, "https://example.com/tracker?demo=tracker_2024_01_01_20_00_00.PRdemo")
+			} else {
+				require.Len(t, urls, 1)
+			}
+		})
+	}
+}
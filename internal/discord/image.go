@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/emilekm/artifacts-mover/internal/summary"
 	"github.com/fogleman/gg"
 	"github.com/golang/freetype/truetype"
 )
@@ -28,17 +29,49 @@ const (
 	gpmInsurgency = "gpm_insurgency"
 	gpmGungame    = "gpm_gungame"
 
+	fontTypeRegular      = "Regular"
 	fontTypeBold         = "Bold"
 	fontTypeMediumItalic = "MediumItalic"
+
+	layoutCompact  = "compact"
+	layoutExtended = "extended"
+
+	defaultTopPlayers = 5
 )
 
+type imageOptions struct {
+	Layout     string
+	TopPlayers int
+}
+
 //go:embed assets/*
 var assets embed.FS
 
-func createImage(summary *jsonSummary) (io.Reader, error) {
-	dc := gg.NewContext(width, height)
+func createImage(summary *summary.Summary, loc *locale, opts imageOptions) (io.Reader, error) {
+	img, err := renderImage(summary, loc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+
+	err = png.Encode(out, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func renderImage(summary *summary.Summary, loc *locale, opts imageOptions) (image.Image, error) {
+	canvasHeight := height
+	if opts.Layout == layoutExtended {
+		canvasHeight += extendedHeight(opts.TopPlayers)
+	}
+
+	dc := gg.NewContext(width, canvasHeight)
 
-	details, ok := findMapDetails(summary)
+	details, ok := findMapDetails(summary, loc.catalog)
 	if ok {
 		bgImg, err := loadMapTile(details)
 		if err == nil {
@@ -53,13 +86,13 @@ func createImage(summary *jsonSummary) (io.Reader, error) {
 
 	dc.DrawImage(templateImage, 0, 0)
 
-	if err = setFont(dc, 24, fontTypeBold); err != nil {
+	if err = loc.setFont(dc, 24, fontTypeBold); err != nil {
 		return nil, err
 	}
 	dc.SetRGB(1, 1, 1)
 	dc.DrawStringAnchored(fmt.Sprintf("%s (%d km)", details.Name, details.Size), 200, 15, 0.5, 1)
 
-	if err = setFont(dc, 17, fontTypeMediumItalic); err != nil {
+	if err = loc.setFont(dc, 17, fontTypeMediumItalic); err != nil {
 		return nil, err
 	}
 
@@ -67,36 +100,36 @@ func createImage(summary *jsonSummary) (io.Reader, error) {
 	dc.DrawStringAnchored(layerMode, 200, 48, 0.5, 0.5)
 
 	if summary.MapMode == gpmGungame {
-		err = drawGGWinner(dc, findGGWinner(summary.Players))
+		err = drawGGWinner(dc, loc, findGGWinner(summary.Players))
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err = drawTickets(dc, summary)
+		err = drawTickets(dc, loc, summary)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	out := &bytes.Buffer{}
-
-	err = png.Encode(out, dc.Image())
-	if err != nil {
-		return nil, err
+	if opts.Layout == layoutExtended {
+		err = drawExtended(dc, loc, summary, opts.TopPlayers)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return out, nil
+	return dc.Image(), nil
 }
 
-func drawGGWinner(dc *gg.Context, winner string) error {
-	err := setFont(dc, 13, fontTypeBold)
+func drawGGWinner(dc *gg.Context, loc *locale, winner string) error {
+	err := loc.setFont(dc, 13, fontTypeBold)
 	if err != nil {
 		return err
 	}
 
-	dc.DrawStringAnchored("Winner:", 200, 70, 0.5, 0.5)
+	dc.DrawStringAnchored(loc.text.Winner, 200, 70, 0.5, 0.5)
 
-	err = setFont(dc, 19, fontTypeBold)
+	err = loc.setFont(dc, 19, fontTypeBold)
 	if err != nil {
 		return err
 	}
@@ -105,8 +138,8 @@ func drawGGWinner(dc *gg.Context, winner string) error {
 	return nil
 }
 
-func findGGWinner(players []player) string {
-	var winner player
+func findGGWinner(players []summary.Player) string {
+	var winner summary.Player
 
 	for _, p := range players {
 		if p.Score > winner.Score {
@@ -117,8 +150,8 @@ func findGGWinner(players []player) string {
 	return winner.Name
 }
 
-func drawTickets(dc *gg.Context, summary *jsonSummary) error {
-	if err := setFont(dc, 34, fontTypeBold); err != nil {
+func drawTickets(dc *gg.Context, loc *locale, summary *summary.Summary) error {
+	if err := loc.setFont(dc, 34, fontTypeBold); err != nil {
 		return err
 	}
 	dc.DrawStringAnchored(strconv.Itoa(summary.Team2Tickets), 161, 62, 0.5, 1)
@@ -189,7 +222,7 @@ type mapDetails struct {
 	layer    string
 }
 
-func findMapDetails(summary *jsonSummary) (mapDetails, bool) {
+func findMapDetails(summary *summary.Summary, cat *catalog) (mapDetails, bool) {
 	found := true
 
 	m, ok := levels[summary.MapName]
@@ -198,12 +231,12 @@ func findMapDetails(summary *jsonSummary) (mapDetails, bool) {
 		m.Name = summary.MapName
 	}
 
-	gm, ok := gameModes[summary.MapMode]
+	gm, ok := cat.gameMode(summary.MapMode)
 	if !ok {
 		gm.Name = summary.MapMode
 	}
 
-	l, ok := layers[summary.MapLayer]
+	l, ok := cat.layer(summary.MapLayer)
 	if !ok {
 		l = strconv.Itoa(summary.MapLayer)
 	}
@@ -215,11 +248,10 @@ func findMapDetails(summary *jsonSummary) (mapDetails, bool) {
 	}, found
 }
 
-func setFont(dc *gg.Context, size float64, typ string) error {
-	fontBytes, err := assets.ReadFile(path.Join("assets", fmt.Sprintf("OpenSans-%s.ttf", typ)))
-	f, err := truetype.Parse(fontBytes)
-	if err != nil {
-		return err
+func (l *locale) setFont(dc *gg.Context, size float64, typ string) error {
+	f, ok := l.fonts[typ]
+	if !ok {
+		return fmt.Errorf("unknown font type %s", typ)
 	}
 
 	face := truetype.NewFace(f, &truetype.Options{
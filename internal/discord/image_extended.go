@@ -0,0 +1,162 @@
+package discord
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emilekm/artifacts-mover/internal/summary"
+	"github.com/fogleman/gg"
+)
+
+const (
+	extendedDurationHeight = 24
+	extendedHeaderHeight   = 30
+	playerRowHeight        = 18
+	extendedPadding        = 8
+
+	smallFlagWidth  = 25
+	smallFlagHeight = 14
+)
+
+func extendedHeight(topPlayers int) int {
+	return extendedDurationHeight + extendedHeaderHeight + topPlayers*playerRowHeight + extendedPadding
+}
+
+// drawExtended renders the duration, team scores and top players below the compact card.
+// Team 2 is on the left and team 1 on the right, matching the tickets above.
+func drawExtended(dc *gg.Context, loc *locale, s *summary.Summary, topPlayers int) error {
+	top := float64(height)
+
+	dc.SetRGB(0.12, 0.12, 0.12)
+	dc.DrawRectangle(0, top, width, float64(extendedHeight(topPlayers)))
+	dc.Fill()
+
+	if err := loc.setFont(dc, 13, fontTypeMediumItalic); err != nil {
+		return err
+	}
+
+	dc.SetRGB(1, 1, 1)
+	dc.DrawStringAnchored(fmt.Sprintf(loc.text.Duration, (s.EndTime-s.StartTime)/60), width/2, top+extendedDurationHeight/2, 0.5, 0.5)
+
+	top += extendedDurationHeight
+
+	// Without team assignments the players are ranked together, as in Gungame.
+	if s.MapMode == gpmGungame || !hasTeams(s.Players) {
+		return drawPlayerRows(dc, loc, topScorers(s.Players, topPlayers), 0, top, width)
+	}
+
+	teams := []struct {
+		name    string
+		tickets int
+		number  int
+		x       float64
+	}{
+		{name: s.Team2Name, tickets: s.Team2Tickets, number: 2, x: 0},
+		{name: s.Team1Name, tickets: s.Team1Tickets, number: 1, x: width / 2},
+	}
+
+	for i, team := range teams {
+		other := teams[1-i]
+
+		if team.tickets > other.tickets {
+			dc.SetRGBA(0.26, 0.96, 0.59, 0.2)
+			dc.DrawRectangle(team.x, top, width/2, float64(extendedHeight(topPlayers)-extendedDurationHeight))
+			dc.Fill()
+		}
+
+		players := make([]summary.Player, 0)
+		teamScore := 0
+		for _, p := range s.Players {
+			if p.Team == team.number {
+				players = append(players, p)
+				teamScore += p.Score
+			}
+		}
+
+		flagImg, err := loadImage(strings.ToLower(team.name) + ".png")
+		if err != nil {
+			flagImg, _ = loadImage("Blank.png")
+		}
+
+		drawScaledImage(dc, flagImg, team.x+extendedPadding, top+(extendedHeaderHeight-smallFlagHeight)/2, smallFlagWidth, smallFlagHeight)
+
+		if err := loc.setFont(dc, 15, fontTypeBold); err != nil {
+			return err
+		}
+
+		dc.SetRGB(1, 1, 1)
+		dc.DrawStringAnchored(strconv.Itoa(teamScore), team.x+width/2-extendedPadding, top+extendedHeaderHeight/2, 1, 0.5)
+
+		err = drawPlayerRows(dc, loc, topScorers(players, topPlayers), team.x, top+extendedHeaderHeight, width/2)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func drawPlayerRows(dc *gg.Context, loc *locale, players []summary.Player, x, y, w float64) error {
+	if err := loc.setFont(dc, 12, fontTypeRegular); err != nil {
+		return err
+	}
+
+	dc.SetRGB(1, 1, 1)
+
+	for i, p := range players {
+		rowY := y + float64(i)*playerRowHeight + playerRowHeight/2
+		score := strconv.Itoa(p.Score)
+
+		scoreW, _ := dc.MeasureString(score)
+		name := truncate(dc, fmt.Sprintf("%d. %s", i+1, p.Name), w-3*extendedPadding-scoreW)
+
+		dc.DrawStringAnchored(name, x+extendedPadding, rowY, 0, 0.5)
+		dc.DrawStringAnchored(score, x+w-extendedPadding, rowY, 1, 0.5)
+	}
+
+	return nil
+}
+
+func hasTeams(players []summary.Player) bool {
+	return slices.ContainsFunc(players, func(p summary.Player) bool {
+		return p.Team != 0
+	})
+}
+
+// topScorers returns up to n players with the highest score, ties are ordered by name.
+func topScorers(players []summary.Player, n int) []summary.Player {
+	sorted := slices.Clone(players)
+	slices.SortStableFunc(sorted, func(a, b summary.Player) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	return sorted
+}
+
+// truncate shortens s with an ellipsis so that it fits into maxWidth.
+func truncate(dc *gg.Context, s string, maxWidth float64) string {
+	if w, _ := dc.MeasureString(s); w <= maxWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := string(runes) + "…"
+		if w, _ := dc.MeasureString(candidate); w <= maxWidth {
+			return candidate
+		}
+	}
+
+	return ""
+}
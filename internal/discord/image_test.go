@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden images")
+
+func TestRenderImage(t *testing.T) {
+	players := []summary.Player{
+		{Name: "Alpha", Score: 120, Team: 1},
+		{Name: "Bravo", Score: 80, Team: 1},
+		{Name: "Charlie with a very long name that does not fit", Score: 95, Team: 1},
+		{Name: "Delta", Score: 30, Team: 2},
+		{Name: "Echo", Score: 150, Team: 2},
+		{Name: "Foxtrot", Score: 10, Team: 2},
+	}
+
+	aas := &summary.Summary{
+		MapName:      "muttrah_city_2",
+		MapMode:      "gpm_cq",
+		MapLayer:     64,
+		Team1Name:    "MEC",
+		Team2Name:    "USA",
+		Team1Tickets: 120,
+		Team2Tickets: 35,
+		StartTime:    1700000000,
+		EndTime:      1700003600,
+		Players:      players,
+	}
+
+	noTeams := *aas
+	noTeams.Players = make([]summary.Player, len(players))
+	for i, p := range players {
+		p.Team = 0
+		noTeams.Players[i] = p
+	}
+
+	gungame := &summary.Summary{
+		MapName:   "muttrah_city_2",
+		MapMode:   "gpm_gungame",
+		MapLayer:  16,
+		StartTime: 1700000000,
+		EndTime:   1700001200,
+		Players:   players,
+	}
+
+	tests := []struct {
+		name    string
+		summary *summary.Summary
+		locale  string
+		opts    imageOptions
+	}{
+		{name: "compact", summary: aas, opts: imageOptions{Layout: layoutCompact}},
+		{name: "extended", summary: aas, opts: imageOptions{Layout: layoutExtended, TopPlayers: 2}},
+		{name: "extended_gungame", summary: gungame, opts: imageOptions{Layout: layoutExtended, TopPlayers: 3}},
+		{name: "extended_no_teams", summary: &noTeams, opts: imageOptions{Layout: layoutExtended, TopPlayers: 3}},
+		{name: "extended_ru", summary: aas, locale: "ru", opts: imageOptions{Layout: layoutExtended, TopPlayers: 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loc, err := newLocale(test.locale, config.DiscordFonts{})
+			require.NoError(t, err)
+
+			img, err := renderImage(test.summary, loc, test.opts)
+			require.NoError(t, err)
+
+			compareGolden(t, test.name, img)
+		})
+	}
+}
+
+func compareGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name+".png")
+
+	if *update {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, img))
+		require.NoError(t, os.MkdirAll("testdata", 0755))
+		require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+		return
+	}
+
+	file, err := os.Open(golden)
+	require.NoError(t, err)
+	defer file.Close()
+
+	expected, err := png.Decode(file)
+	require.NoError(t, err)
+
+	require.Equal(t, toRGBA(expected), toRGBA(img), "image differs from %s, run with -update to regenerate", golden)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+func TestRenderTicketChart(t *testing.T) {
+	samples := []summary.TicketSample{
+		{Time: 0, Team1: 300, Team2: 300},
+		{Time: 10 * time.Minute, Team1: 280, Team2: 300},
+		{Time: 25 * time.Minute, Team1: 280, Team2: 240},
+		{Time: 40 * time.Minute, Team1: 150, Team2: 240},
+		{Time: 55 * time.Minute, Team1: 150, Team2: 60},
+		{Time: 62 * time.Minute, Team1: 120, Team2: 0},
+	}
+
+	loc, err := newLocale("", config.DiscordFonts{})
+	require.NoError(t, err)
+
+	img, err := renderTicketChart(samples, "MEC", "USA", loc)
+	require.NoError(t, err)
+
+	compareGolden(t, "ticket_chart", img)
+}
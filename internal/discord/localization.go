@@ -1,5 +1,14 @@
 package discord
 
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/golang/freetype/truetype"
+)
+
 type gameMode struct {
 	Name  string
 	Color int
@@ -50,3 +59,198 @@ var (
 		},
 	}
 )
+
+// catalog holds the translations of one locale. Missing layer and game mode
+// names fall back to English.
+type catalog struct {
+	layers    map[int]string
+	gameModes map[string]string
+	text      catalogText
+}
+
+type catalogText struct {
+	Description string
+
+	BF2DemoButton string
+	PRDemoButton  string
+	TrackerButton string
+
+	RoundEnded      string
+	Archiving       string
+	ArchivingFailed string
+
+	Winner string
+	// Duration is a fmt format taking the round duration in minutes.
+	Duration string
+	// Minutes is a fmt format for the time axis of the ticket chart.
+	Minutes string
+	// Incomplete is a fmt format taking the artifact types that failed validation.
+	Incomplete string
+}
+
+const defaultLocale = "en"
+
+var catalogs = map[string]*catalog{
+	"en": {
+		text: catalogText{
+			Description: defaultDescriptionTemplate,
+
+			BF2DemoButton: defaultBF2DemoButtonTemplate,
+			PRDemoButton:  defaultPRDemoButtonTemplate,
+			TrackerButton: defaultTrackerButtonTemplate,
+
+			RoundEnded:      "Round ended",
+			Archiving:       "Archiving…",
+			ArchivingFailed: "Archiving failed, files will be uploaded later.",
+
+			Winner:   "Winner:",
+			Duration: "Duration: %d min",
+			Minutes:  "%d min",
+
+			Incomplete: "⚠️ Incomplete round, damaged files: %s",
+		},
+	},
+	"pl": {
+		layers: map[int]string{
+			16:  "Piechota",
+			32:  "Alternatywna",
+			64:  "Standardowa",
+			128: "Duża",
+		},
+		gameModes: map[string]string{
+			"gpm_cq":         "Szturm i zabezpieczenie",
+			"gpm_cnc":        "Dowodzenie i kontrola",
+			"gpm_coop":       "Kooperacja",
+			"gpm_insurgency": "Rebelia",
+			"gpm_skirmish":   "Potyczka",
+			"gpm_vehicles":   "Wojna pojazdów",
+		},
+		text: catalogText{
+			Description: `**_{{.Mode.Name}}, {{.Layer}}_**
+
+Czas trwania: {{.DurationMinutes}} min
+Rozpoczęcie: <t:{{.StartTime.Unix}}:R> | <t:{{.StartTime.Unix}}:F>
+Zakończenie: <t:{{.EndTime.Unix}}:R> | <t:{{.EndTime.Unix}}:F>`,
+
+			BF2DemoButton: "Pobierz Battle Recorder",
+			PRDemoButton:  "Pobierz Tracker",
+			TrackerButton: "Zobacz Tracker",
+
+			RoundEnded:      "Runda zakończona",
+			Archiving:       "Archiwizowanie…",
+			ArchivingFailed: "Archiwizacja nie powiodła się, pliki zostaną przesłane później.",
+
+			Winner:   "Zwycięzca:",
+			Duration: "Czas trwania: %d min",
+			Minutes:  "%d min",
+
+			Incomplete: "⚠️ Niekompletna runda, uszkodzone pliki: %s",
+		},
+	},
+	"ru": {
+		layers: map[int]string{
+			16:  "Пехотный",
+			32:  "Альтернативный",
+			64:  "Стандартный",
+			128: "Большой",
+		},
+		gameModes: map[string]string{
+			"gpm_cq":         "Штурм и захват",
+			"gpm_cnc":        "Командование и контроль",
+			"gpm_coop":       "Кооператив",
+			"gpm_insurgency": "Повстанцы",
+			"gpm_skirmish":   "Стычка",
+			"gpm_vehicles":   "Война техники",
+		},
+		text: catalogText{
+			Description: `**_{{.Mode.Name}}, {{.Layer}}_**
+
+Длительность: {{.DurationMinutes}} мин
+Начало: <t:{{.StartTime.Unix}}:R> | <t:{{.StartTime.Unix}}:F>
+Конец: <t:{{.EndTime.Unix}}:R> | <t:{{.EndTime.Unix}}:F>`,
+
+			BF2DemoButton: "Скачать Battle Recorder",
+			PRDemoButton:  "Скачать трекер",
+			TrackerButton: "Открыть трекер",
+
+			RoundEnded:      "Раунд завершён",
+			Archiving:       "Архивация…",
+			ArchivingFailed: "Архивация не удалась, файлы будут загружены позже.",
+
+			Winner:   "Победитель:",
+			Duration: "Длительность: %d мин",
+			Minutes:  "%d мин",
+
+			Incomplete: "⚠️ Неполный раунд, повреждённые файлы: %s",
+		},
+	},
+}
+
+func (c *catalog) layer(layer int) (string, bool) {
+	if l, ok := c.layers[layer]; ok {
+		return l, true
+	}
+
+	l, ok := layers[layer]
+	return l, ok
+}
+
+func (c *catalog) gameMode(mode string) (gameMode, bool) {
+	gm, ok := gameModes[mode]
+	if name, translated := c.gameModes[mode]; translated {
+		gm.Name = name
+	}
+
+	return gm, ok
+}
+
+// locale is everything needed to render posts and images in one language.
+type locale struct {
+	*catalog
+	fonts map[string]*truetype.Font
+}
+
+// newLocale looks up the catalog of name and loads the fonts, fonts not
+// overridden in conf come from the embedded OpenSans faces.
+func newLocale(name string, conf config.DiscordFonts) (*locale, error) {
+	if name == "" {
+		name = defaultLocale
+	}
+
+	cat, ok := catalogs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown locale %s", name)
+	}
+
+	loc := &locale{
+		catalog: cat,
+		fonts:   make(map[string]*truetype.Font),
+	}
+
+	for typ, override := range map[string]string{
+		fontTypeRegular:      conf.Regular,
+		fontTypeBold:         conf.Bold,
+		fontTypeMediumItalic: conf.MediumItalic,
+	} {
+		var (
+			fontBytes []byte
+			err       error
+		)
+
+		if override != "" {
+			fontBytes, err = os.ReadFile(override)
+		} else {
+			fontBytes, err = assets.ReadFile(path.Join("assets", fmt.Sprintf("OpenSans-%s.ttf", typ)))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		loc.fonts[typ], err = truetype.Parse(fontBytes)
+		if err != nil {
+			return nil, fmt.Errorf("font %s: %w", typ, err)
+		}
+	}
+
+	return loc, nil
+}
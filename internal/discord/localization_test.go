@@ -0,0 +1,34 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocales(t *testing.T) {
+	for name := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			loc, err := newLocale(name, config.DiscordFonts{})
+			require.NoError(t, err)
+
+			_, err = parseTemplates(config.DiscordTemplates{}, loc.catalog)
+			require.NoError(t, err)
+
+			for _, text := range []string{loc.text.Winner, loc.text.RoundEnded, loc.text.Archiving} {
+				for _, r := range text {
+					for typ, f := range loc.fonts {
+						require.NotZero(t, f.Index(r), "font %s misses %q", typ, r)
+					}
+				}
+			}
+		})
+	}
+
+	_, err := newLocale("xx", config.DiscordFonts{})
+	require.Error(t, err)
+
+	_, err = newLocale("pl", config.DiscordFonts{Bold: "missing.ttf"})
+	require.Error(t, err)
+}
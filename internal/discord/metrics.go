@@ -0,0 +1,30 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
+// instrumentedSession counts the Discord API requests in the metrics.
+type instrumentedSession struct {
+	discordSession
+}
+
+func (s instrumentedSession) ChannelMessageSendComplex(channelID string, msg *discordgo.MessageSend, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	m, err := s.discordSession.ChannelMessageSendComplex(channelID, msg, opts...)
+	observeRequest("send", err)
+	return m, err
+}
+
+func (s instrumentedSession) ChannelMessageEditComplex(msg *discordgo.MessageEdit, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	m, err := s.discordSession.ChannelMessageEditComplex(msg, opts...)
+	observeRequest("edit", err)
+	return m, err
+}
+
+func observeRequest(method string, err error) {
+	metrics.DiscordRequests.WithLabelValues(method).Inc()
+	if err != nil {
+		metrics.DiscordFailures.WithLabelValues(method).Inc()
+	}
+}
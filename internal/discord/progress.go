@@ -0,0 +1,143 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/emilekm/artifacts-mover/internal"
+)
+
+const (
+	placeholderColor = 0x858585
+	failedColor      = 0xF54242
+)
+
+// pendingMessage is the placeholder message of a round whose upload is in progress.
+// ready is closed once the placeholder was posted, messageID stays empty if posting failed.
+type pendingMessage struct {
+	ready     chan struct{}
+	messageID string
+}
+
+func (w *Client) RoundSealed(ctx context.Context, round internal.Round) error {
+	if !w.placeholder {
+		return nil
+	}
+
+	pending := &pendingMessage{ready: make(chan struct{})}
+	defer close(pending.ready)
+
+	w.mu.Lock()
+	w.pending[round.Key()] = pending
+	w.mu.Unlock()
+
+	msg, err := w.session.ChannelMessageSendComplex(w.channelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       w.locale.text.RoundEnded,
+			Description: w.locale.text.Archiving,
+			Type:        discordgo.EmbedTypeRich,
+			Color:       placeholderColor,
+		}},
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	pending.messageID = msg.ID
+
+	return nil
+}
+
+func (w *Client) RoundFailed(ctx context.Context, round internal.Round, uploadErr error) error {
+	messageID, err := w.waitPending(ctx, round)
+	if err != nil || messageID == "" {
+		return err
+	}
+
+	embeds := []*discordgo.MessageEmbed{{
+		Title:       w.locale.text.RoundEnded,
+		Description: fmt.Sprintf("%s\n```\n%s\n```", w.locale.text.ArchivingFailed, uploadErr),
+		Type:        discordgo.EmbedTypeRich,
+		Color:       failedColor,
+	}}
+
+	_, err = w.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:      messageID,
+		Channel: w.channelID,
+		Embeds:  &embeds,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	w.forgetPending(round)
+
+	return nil
+}
+
+// waitPending waits for the placeholder of the round to be posted. It returns an empty
+// ID when the round has no placeholder. The placeholder is kept until it was edited,
+// so a retried notification edits it instead of posting another message.
+func (w *Client) waitPending(ctx context.Context, round internal.Round) (string, error) {
+	w.mu.Lock()
+	pending, ok := w.pending[round.Key()]
+	w.mu.Unlock()
+
+	if !ok {
+		return "", nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-pending.ready:
+	}
+
+	if pending.messageID == "" {
+		// Posting the placeholder failed, there is nothing to edit.
+		w.forgetPending(round)
+	}
+
+	return pending.messageID, nil
+}
+
+func (w *Client) forgetPending(round internal.Round) {
+	w.mu.Lock()
+	delete(w.pending, round.Key())
+	w.mu.Unlock()
+}
+
+// sendOrEdit replaces the placeholder of the round with msg, or posts msg
+// when there is no placeholder.
+func (w *Client) sendOrEdit(ctx context.Context, round internal.Round, msg *discordgo.MessageSend) error {
+	messageID, err := w.waitPending(ctx, round)
+	if err != nil {
+		return err
+	}
+
+	if messageID == "" {
+		_, err = w.session.ChannelMessageSendComplex(w.channelID, msg, discordgo.WithContext(ctx))
+		return err
+	}
+
+	content := msg.Content
+	attachments := make([]*discordgo.MessageAttachment, 0)
+
+	_, err = w.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:          messageID,
+		Channel:     w.channelID,
+		Content:     &content,
+		Embeds:      &msg.Embeds,
+		Components:  &msg.Components,
+		Files:       msg.Files,
+		Attachments: &attachments,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	w.forgetPending(round)
+
+	return nil
+}
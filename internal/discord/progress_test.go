@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/emilekm/artifacts-mover/internal"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSession records messages, the first failEdits edits fail.
+type fakeSession struct {
+	mu        sync.Mutex
+	failEdits int
+	sent      []*discordgo.MessageSend
+	edits     []*discordgo.MessageEdit
+}
+
+func (s *fakeSession) ChannelMessageSendComplex(channelID string, msg *discordgo.MessageSend, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, msg)
+	return &discordgo.Message{ID: "placeholder", ChannelID: channelID}, nil
+}
+
+func (s *fakeSession) ChannelMessageEditComplex(msg *discordgo.MessageEdit, opts ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failEdits > 0 {
+		s.failEdits--
+		return nil, errors.New("timeout")
+	}
+
+	s.edits = append(s.edits, msg)
+	return &discordgo.Message{ID: msg.ID, ChannelID: msg.Channel}, nil
+}
+
+func TestPlaceholder(t *testing.T) {
+	round := internal.Round{
+		config.ArtifactTypeBF2Demo: {Path: "bf2demos/round1.bf2demo", Type: config.ArtifactTypeBF2Demo},
+	}
+
+	t.Run("edited on success", func(t *testing.T) {
+		session := &fakeSession{}
+		client, err := New(session, config.Discord{ChannelID: "channel", Placeholder: true}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.RoundSealed(context.Background(), round))
+		require.NoError(t, client.Send(context.Background(), round))
+
+		require.Len(t, session.sent, 1)
+		require.Len(t, session.edits, 1)
+		require.Equal(t, "placeholder", session.edits[0].ID)
+		require.Len(t, *session.edits[0].Components, 1)
+	})
+
+	t.Run("edited on failure", func(t *testing.T) {
+		session := &fakeSession{}
+		client, err := New(session, config.Discord{ChannelID: "channel", Placeholder: true}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.RoundSealed(context.Background(), round))
+		require.NoError(t, client.RoundFailed(context.Background(), round, errors.New("connection refused")))
+
+		require.Len(t, session.edits, 1)
+		require.Equal(t, failedColor, (*session.edits[0].Embeds)[0].Color)
+	})
+
+	t.Run("edit retried", func(t *testing.T) {
+		session := &fakeSession{failEdits: 1}
+		client, err := New(session, config.Discord{ChannelID: "channel", Placeholder: true}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.RoundSealed(context.Background(), round))
+		require.Error(t, client.Send(context.Background(), round))
+		require.NoError(t, client.Send(context.Background(), round))
+
+		require.Len(t, session.sent, 1)
+		require.Len(t, session.edits, 1)
+		require.Empty(t, client.pending)
+	})
+
+	t.Run("failure edit retried", func(t *testing.T) {
+		session := &fakeSession{failEdits: 1}
+		client, err := New(session, config.Discord{ChannelID: "channel", Placeholder: true}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.RoundSealed(context.Background(), round))
+		require.Error(t, client.RoundFailed(context.Background(), round, errors.New("connection refused")))
+		require.NoError(t, client.RoundFailed(context.Background(), round, errors.New("connection refused")))
+
+		require.Len(t, session.edits, 1)
+		require.Empty(t, client.pending)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		session := &fakeSession{}
+		client, err := New(session, config.Discord{ChannelID: "channel"}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.RoundSealed(context.Background(), round))
+		require.NoError(t, client.Send(context.Background(), round))
+
+		require.Len(t, session.sent, 1)
+		require.Empty(t, session.edits)
+	})
+}
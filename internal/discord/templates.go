@@ -0,0 +1,176 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+)
+
+const (
+	defaultTitleTemplate       = `{{.Map.Name}} ({{.Map.Size}} km)`
+	defaultDescriptionTemplate = `**_{{.Mode.Name}}, {{.Layer}}_**
+
+Duration: {{.DurationMinutes}} minutes
+Started: <t:{{.StartTime.Unix}}:R> | <t:{{.StartTime.Unix}}:F>
+Ended: <t:{{.EndTime.Unix}}:R> | <t:{{.EndTime.Unix}}:F>`
+
+	defaultBF2DemoButtonTemplate = `Download Battle Recorder`
+	defaultPRDemoButtonTemplate  = `Download Tracker`
+	defaultTrackerButtonTemplate = `View Tracker`
+)
+
+// templateData is exposed to the message templates.
+type templateData struct {
+	// Summary is nil when the round has no summary artifact.
+	Summary *summary.Summary
+	Map     level
+	Mode    gameMode
+	Layer   string
+
+	StartTime       time.Time
+	EndTime         time.Time
+	Duration        time.Duration
+	DurationMinutes int64
+
+	// URLs maps artifact type, and "tracker", to its public URL.
+	URLs map[string]string
+}
+
+type templates struct {
+	title       *template.Template
+	description *template.Template
+	footer      *template.Template
+	color       *template.Template
+	buttons     map[string]*template.Template
+	// defaultButtons are the labels of the locale, used when a button template fails.
+	defaultButtons map[string]string
+}
+
+func parseTemplates(conf config.DiscordTemplates, cat *catalog) (*templates, error) {
+	t := &templates{
+		buttons: make(map[string]*template.Template),
+		defaultButtons: map[string]string{
+			config.ArtifactTypeBF2Demo.String(): cat.text.BF2DemoButton,
+			config.ArtifactTypePRDemo.String():  cat.text.PRDemoButton,
+			trackerType:                         cat.text.TrackerButton,
+		},
+	}
+
+	var err error
+
+	parse := func(name, text, fallback string) *template.Template {
+		if err != nil {
+			return nil
+		}
+
+		if text == "" {
+			text = fallback
+		}
+
+		if text == "" {
+			return nil
+		}
+
+		var tmpl *template.Template
+		tmpl, err = template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			err = fmt.Errorf("template %s: %w", name, err)
+		}
+		return tmpl
+	}
+
+	t.title = parse("title", conf.Title, defaultTitleTemplate)
+	t.description = parse("description", conf.Description, cat.text.Description)
+	t.footer = parse("footer", conf.Footer, "")
+	t.color = parse("color", conf.Color, "")
+	t.buttons[config.ArtifactTypeBF2Demo.String()] = parse("buttons.bf2demo", conf.Buttons.BF2Demo, cat.text.BF2DemoButton)
+	t.buttons[config.ArtifactTypePRDemo.String()] = parse("buttons.prdemo", conf.Buttons.PRDemo, cat.text.PRDemoButton)
+	t.buttons[trackerType] = parse("buttons.tracker", conf.Buttons.Tracker, cat.text.TrackerButton)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func newTemplateData(s *summary.Summary, cat *catalog, urls map[string]string) *templateData {
+	data := &templateData{
+		Summary: s,
+		URLs:    urls,
+	}
+
+	if s == nil {
+		return data
+	}
+
+	details, _ := findMapDetails(s, cat)
+
+	data.Map = details.level
+	data.Mode = details.gameMode
+	data.Layer = details.layer
+	data.StartTime = time.Unix(s.StartTime, 0)
+	data.EndTime = time.Unix(s.EndTime, 0)
+	data.Duration = data.EndTime.Sub(data.StartTime)
+	data.DurationMinutes = (s.EndTime - s.StartTime) / 60
+
+	return data
+}
+
+// execute renders tmpl, a nil template renders to an empty string.
+func execute(tmpl *template.Template, data *templateData) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// button renders the label of the button of typ. Rounds without a summary leave
+// .Summary nil, so a template failing on it falls back to the default label
+// instead of failing the post.
+func (t *templates) button(typ string, data *templateData) string {
+	label, err := execute(t.buttons[typ], data)
+	if err != nil || label == "" {
+		if err != nil {
+			slog.Warn("button template failed, using the default label", "button", typ, "err", err, "op", "templates.button")
+		}
+		return t.defaultButtons[typ]
+	}
+
+	return label
+}
+
+// parseColor accepts colours as "#RRGGBB", "0xRRGGBB" or a decimal number.
+func parseColor(s string) (int, error) {
+	s = strings.TrimSpace(s)
+
+	var (
+		c   int64
+		err error
+	)
+
+	switch {
+	case strings.HasPrefix(s, "#"):
+		c, err = strconv.ParseInt(s[1:], 16, 32)
+	default:
+		c, err = strconv.ParseInt(s, 0, 32)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+
+	return int(c), nil
+}
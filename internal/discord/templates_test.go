@@ -0,0 +1,119 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	s := &summary.Summary{
+		MapName:   "muttrah_city_2",
+		MapMode:   "gpm_cq",
+		MapLayer:  64,
+		StartTime: 1700000000,
+		EndTime:   1700003600,
+	}
+	data := newTemplateData(s, catalogs[defaultLocale], map[string]string{"bf2demo": "https://example.com/round1.bf2demo"})
+
+	t.Run("defaults", func(t *testing.T) {
+		tmpls, err := parseTemplates(config.DiscordTemplates{}, catalogs[defaultLocale])
+		require.NoError(t, err)
+
+		title, err := execute(tmpls.title, data)
+		require.NoError(t, err)
+		require.Equal(t, "Muttrah City (2 km)", title)
+
+		description, err := execute(tmpls.description, data)
+		require.NoError(t, err)
+		require.Contains(t, description, "**_Assault & Secure, Standard_**")
+		require.Contains(t, description, "Duration: 60 minutes")
+
+		footer, err := execute(tmpls.footer, data)
+		require.NoError(t, err)
+		require.Empty(t, footer)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		tmpls, err := parseTemplates(config.DiscordTemplates{
+			Title:  "{{.Map.Name}} - {{.Summary.MapLayer}}",
+			Footer: "{{.Duration}}",
+			Color:  `{{if eq .Mode.Name "Assault & Secure"}}#ff0000{{end}}`,
+			Buttons: config.DiscordButtons{
+				BF2Demo: "Pobierz ({{index .URLs \"bf2demo\"}})",
+			},
+		}, catalogs[defaultLocale])
+		require.NoError(t, err)
+
+		title, err := execute(tmpls.title, data)
+		require.NoError(t, err)
+		require.Equal(t, "Muttrah City - 64", title)
+
+		footer, err := execute(tmpls.footer, data)
+		require.NoError(t, err)
+		require.Equal(t, "1h0m0s", footer)
+
+		color, err := execute(tmpls.color, data)
+		require.NoError(t, err)
+		c, err := parseColor(color)
+		require.NoError(t, err)
+		require.Equal(t, 0xFF0000, c)
+
+		label, err := execute(tmpls.buttons["bf2demo"], data)
+		require.NoError(t, err)
+		require.Equal(t, "Pobierz (https://example.com/round1.bf2demo)", label)
+	})
+
+	t.Run("localised", func(t *testing.T) {
+		cat := catalogs["pl"]
+		tmpls, err := parseTemplates(config.DiscordTemplates{}, cat)
+		require.NoError(t, err)
+
+		description, err := execute(tmpls.description, newTemplateData(s, cat, nil))
+		require.NoError(t, err)
+		require.Contains(t, description, "**_Szturm i zabezpieczenie, Standardowa_**")
+		require.Contains(t, description, "Czas trwania: 60 min")
+
+		label, err := execute(tmpls.buttons["tracker"], data)
+		require.NoError(t, err)
+		require.Equal(t, "Zobacz Tracker", label)
+	})
+
+	t.Run("button without summary", func(t *testing.T) {
+		tmpls, err := parseTemplates(config.DiscordTemplates{
+			Buttons: config.DiscordButtons{
+				BF2Demo: "{{.Summary.MapName}} BR",
+				PRDemo:  "Tracker ({{index .URLs \"prdemo\"}})",
+			},
+		}, catalogs[defaultLocale])
+		require.NoError(t, err)
+
+		noSummary := newTemplateData(nil, catalogs[defaultLocale], map[string]string{"prdemo": "https://example.com/round1.prdemo"})
+
+		require.Equal(t, "Download Battle Recorder", tmpls.button("bf2demo", noSummary))
+		require.Equal(t, "Tracker (https://example.com/round1.prdemo)", tmpls.button("prdemo", noSummary))
+		require.Equal(t, "muttrah_city_2 BR", tmpls.button("bf2demo", data))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseTemplates(config.DiscordTemplates{Title: "{{.Map.Name"}, catalogs[defaultLocale])
+		require.Error(t, err)
+	})
+}
+
+func TestParseColor(t *testing.T) {
+	for in, expected := range map[string]int{
+		"#4284F5":  0x4284F5,
+		"0x4284F5": 0x4284F5,
+		"4359413":  4359413,
+	} {
+		c, err := parseColor(in)
+		require.NoError(t, err)
+		require.Equal(t, expected, c, in)
+	}
+
+	_, err := parseColor("blue")
+	require.Error(t, err)
+}
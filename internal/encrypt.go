@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+// parseRecipients parses the age and SSH public keys of conf.
+func parseRecipients(conf *config.Encryption) ([]age.Recipient, error) {
+	if len(conf.Recipients) == 0 {
+		return nil, errors.New("encryption needs at least one recipient")
+	}
+
+	recipients := make([]age.Recipient, 0, len(conf.Recipients))
+	for _, key := range conf.Recipients {
+		var (
+			recipient age.Recipient
+			err       error
+		)
+		if strings.HasPrefix(key, "ssh-") {
+			recipient, err = agessh.ParseRecipient(key)
+		} else {
+			recipient, err = age.ParseX25519Recipient(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", key, err)
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+// compressEncrypt writes r to w compressed with c and, with recipients, encrypted for them.
+func compressEncrypt(w io.Writer, r io.Reader, c config.Compression, recipients []age.Recipient) error {
+	if len(recipients) == 0 {
+		return compress(w, r, c)
+	}
+
+	ew, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if err := compress(ew, r, c); err != nil {
+		ew.Close()
+		return err
+	}
+
+	return ew.Close()
+}
+
+// ParseIdentities reads the age identities, or the unencrypted SSH private key, in the file at path.
+func ParseIdentities(path string) ([]age.Identity, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(string(content), "PRIVATE KEY-----") {
+		identity, err := agessh.ParseIdentity(content)
+		if err != nil {
+			return nil, err
+		}
+
+		return []age.Identity{identity}, nil
+	}
+
+	return age.ParseIdentities(strings.NewReader(string(content)))
+}
+
+// RestoreFile decrypts the file at src, as uploaded with encryption, and decompresses it
+// when its name ends with the extension of a compression. The file is restored under
+// its original name in dir and the path is returned.
+func RestoreFile(src, dir string, identities []age.Identity) (string, error) {
+	name, ok := strings.CutSuffix(filepath.Base(src), ".age")
+	if !ok {
+		return "", fmt.Errorf("%s: not an encrypted artifact, expected the .age extension", src)
+	}
+
+	c := config.CompressionNone
+	for _, candidate := range []config.Compression{config.CompressionGzip, config.CompressionZstd} {
+		if trimmed, ok := strings.CutSuffix(name, candidate.Extension()); ok {
+			name, c = trimmed, candidate
+			break
+		}
+	}
+
+	dst := filepath.Join(dir, name)
+	if _, err := os.Stat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", src, err)
+	}
+
+	dr, err := decompress(r, c)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", src, err)
+	}
+	defer dr.Close()
+
+	if err := writeFile(dst, dr, 0644); err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
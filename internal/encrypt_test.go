@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptRestore(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	identityPath := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600))
+
+	content := bytes.Repeat([]byte("a battle recorder "), 1024)
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	basePath := t.TempDir()
+
+	for name, c := range map[string]config.Compression{
+		"none": config.CompressionNone,
+		"gzip": config.CompressionGzip,
+		"zstd": config.CompressionZstd,
+	} {
+		t.Run(name, func(t *testing.T) {
+			artifacts := config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {
+					UploadPath: name,
+					Compress:   c,
+					Encrypt:    &config.Encryption{Recipients: []string{identity.Recipient().String()}},
+				},
+			}
+
+			uploader, err := NewLocalUploader(config.LocalConfig{BasePath: basePath}, artifacts, nil)
+			require.NoError(t, err)
+
+			round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+			results := uploader.Upload(context.Background(), round)
+			require.NoError(t, results.Err(round))
+
+			encrypted := results[config.ArtifactTypeBF2Demo].RemotePath
+			require.Equal(t, "file1.bf2demo"+c.Extension()+".age", filepath.Base(encrypted))
+
+			uploaded, err := os.ReadFile(encrypted)
+			require.NoError(t, err)
+			require.NotContains(t, string(uploaded), "battle recorder")
+
+			identities, err := ParseIdentities(identityPath)
+			require.NoError(t, err)
+
+			restored, err := RestoreFile(encrypted, t.TempDir(), identities)
+			require.NoError(t, err)
+			require.Equal(t, "file1.bf2demo", filepath.Base(restored))
+
+			actual, err := os.ReadFile(restored)
+			require.NoError(t, err)
+			require.Equal(t, content, actual)
+		})
+	}
+}
+
+func TestEncryptRecipients(t *testing.T) {
+	tests := []struct {
+		name      string
+		recipient string
+		err       string
+	}{
+		{name: "age", recipient: "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+		{name: "ssh", recipient: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHsKLqeplhpW+uObz5dvMgjz1OxfM/XXUB+VHtZ6isGN backup"},
+		{name: "invalid", recipient: "age1invalid", err: "invalid recipient"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRecipients(&config.Encryption{Recipients: []string{tt.recipient}})
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
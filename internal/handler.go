@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
 )
 
 //go:generate go run go.uber.org/mock/mockgen -source=./handler.go -destination=./handler_mock.go -package=internal Notifier
@@ -21,9 +25,33 @@ type Notifier interface {
 
 type Round map[config.ArtifactType]Artifact
 
+// Usable returns the artifacts that can be uploaded and read, i.e. all but the corrupt ones.
+func (r Round) Usable() Round {
+	usable := make(Round, len(r))
+	for typ, artifact := range r {
+		if artifact.Status != ArtifactCorrupt {
+			usable[typ] = artifact
+		}
+	}
+
+	return usable
+}
+
+// Key identifies the round by the paths of its artifacts.
+func (r Round) Key() string {
+	paths := make([]string, 0, len(r))
+	for _, artifact := range r {
+		paths = append(paths, artifact.Path)
+	}
+
+	slices.Sort(paths)
+
+	return strings.Join(paths, "\x00")
+}
+
 type Handler struct {
 	uploader         Uploader
-	notifier         Notifier
+	notifiers        []NotifierSpec
 	artifactsConfig  config.ArtifactsConfig
 	locToTyp         map[string]config.ArtifactType
 	roundTimeout     time.Duration
@@ -32,32 +60,68 @@ type Handler struct {
 	bf2DemoOnly bool
 	typesCount  int
 
+	quarantinePath string
+
+	server      string
+	manifestDir string
+
+	schedules     map[config.ArtifactType]schedule
+	betweenRounds map[config.ArtifactType]bool
+
+	alerters         []Alerter
+	backlogThreshold int
+	backlogMu        sync.Mutex
+	backlogAlerted   bool
+
 	mu           sync.Mutex
 	currentRound Round
-	roundTimer   *time.Timer
-	ctx          context.Context
-	cancel       context.CancelFunc
+	// held are the artifacts waiting for the round in progress to end.
+	held       []Artifact
+	roundTimer *time.Timer
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
 func NewHandler(
 	uploader Uploader,
-	notifier Notifier,
+	notifiers []NotifierSpec,
 	artifactsConfig config.ArtifactsConfig,
 	roundTimeout time.Duration,
 	failedUploadPath string,
+	opts ...HandlerOption,
 ) (*Handler, error) {
 	bf2DemoOnly := true
 
 	locToType := make(map[string]config.ArtifactType)
+	schedules := make(map[config.ArtifactType]schedule)
+	betweenRounds := make(map[config.ArtifactType]bool)
 
 	for typ, location := range artifactsConfig {
 		locToType[filepath.Clean(location.Location)] = typ
 
+		s, err := newSchedule(location.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typ, err)
+		}
+		schedules[typ] = s
+		betweenRounds[typ] = location.Schedule != nil && location.Schedule.BetweenRounds
+
+		if location.Encrypt != nil {
+			if _, err := parseRecipients(location.Encrypt); err != nil {
+				return nil, fmt.Errorf("%s: %w", typ, err)
+			}
+		}
+
 		if typ != config.ArtifactTypeBF2Demo {
 			bf2DemoOnly = false
 		}
 	}
 
+	// Only the PR demo and summary tell that a round ended, a new battle recorder means the next one started.
+	if bf2DemoOnly && betweenRounds[config.ArtifactTypeBF2Demo] {
+		return nil, fmt.Errorf("%s: betweenRounds requires prdemo or summary artifacts", config.ArtifactTypeBF2Demo)
+	}
+
 	for _, artifact := range locToType {
 		failedDir := filepath.Join(failedUploadPath, artifact.String())
 		if err := os.MkdirAll(failedDir, 0755); err != nil {
@@ -67,19 +131,55 @@ func NewHandler(
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Handler{
+	h := &Handler{
 		uploader:         uploader,
-		notifier:         notifier,
+		notifiers:        notifiers,
 		artifactsConfig:  artifactsConfig,
 		locToTyp:         locToType,
 		roundTimeout:     roundTimeout,
 		failedUploadPath: failedUploadPath,
 		bf2DemoOnly:      bf2DemoOnly,
 		typesCount:       len(locToType),
+		schedules:        schedules,
+		betweenRounds:    betweenRounds,
 		currentRound:     make(Round),
 		ctx:              ctx,
 		cancel:           cancel,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.manifestDir != "" {
+		if err := os.MkdirAll(h.manifestDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.quarantinePath != "" {
+		for _, typ := range locToType {
+			if err := os.MkdirAll(filepath.Join(h.quarantinePath, typ.String()), 0755); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Files left over from previous runs count towards the backlog from the start.
+	backlog, err := h.backlog()
+	if err != nil {
+		return nil, err
+	}
+	metrics.FailedBacklog.WithLabelValues(h.server).Set(float64(backlog))
+
+	return h, nil
+}
+
+// WithServer names the server of the handler in manifests and metrics.
+func WithServer(server string) HandlerOption {
+	return func(h *Handler) {
+		h.server = server
+	}
 }
 
 func (h *Handler) OnFileCreate(path string) {
@@ -108,17 +208,19 @@ func (h *Handler) handleFile(artifact Artifact) {
 
 	log.Debug("Handling file")
 
+	metrics.FilesDetected.WithLabelValues(h.server, artifact.Type.String()).Inc()
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	if _, ok := h.currentRound[artifact.Type]; ok {
 		log.Debug("Type already in current round, ending")
-		h.endCurrentRoundLocked()
+		h.endCurrentRoundLocked(metrics.RoundComplete, true)
 	}
 
 	if artifact.Type == config.ArtifactTypeBF2Demo && len(h.currentRound) > 0 {
 		log.Debug("BF2 demo received, ending current round")
-		h.endCurrentRoundLocked()
+		h.endCurrentRoundLocked(metrics.RoundComplete, true)
 	}
 
 	if len(h.currentRound) == 0 && h.roundTimeout > 0 {
@@ -129,7 +231,7 @@ func (h *Handler) handleFile(artifact Artifact) {
 	if !h.bf2DemoOnly && len(h.currentRound) == h.typesCount-1 {
 		log.Debug("All types except one in current round, ending")
 		h.currentRound[artifact.Type] = artifact
-		h.endCurrentRoundLocked()
+		h.endCurrentRoundLocked(metrics.RoundComplete, false)
 		return
 	}
 
@@ -149,12 +251,20 @@ func (h *Handler) startRoundTimer() {
 		defer h.mu.Unlock()
 		if len(h.currentRound) > 0 {
 			slog.Warn("Round timeout reached, ending incomplete round", "files", len(h.currentRound))
-			h.endCurrentRoundLocked()
+			h.alert(Alert{
+				Kind:         AlertRoundTimeout,
+				Round:        maps.Clone(h.currentRound),
+				MissingTypes: h.missingTypesLocked(),
+			})
+			h.endCurrentRoundLocked(metrics.RoundTimeout, false)
 		}
 	})
 }
 
-func (h *Handler) endCurrentRoundLocked() {
+// endCurrentRoundLocked seals the current round and uploads it, reason is recorded
+// in the rounds sealed metric. nextRound tells that the file ending the round is the
+// first of the next one, so that round is already being played.
+func (h *Handler) endCurrentRoundLocked(reason string, nextRound bool) {
 	if h.roundTimer != nil {
 		h.roundTimer.Stop()
 		h.roundTimer = nil
@@ -164,31 +274,98 @@ func (h *Handler) endCurrentRoundLocked() {
 		return
 	}
 
-	err := h.uploader.Upload(h.currentRound)
-	if err != nil {
-		slog.Error("failed to upload round", "err", err, "op", "Handler.endCurrentRound")
-		go h.backupFailedUploads(h.currentRound)
+	round := h.currentRound
+	h.currentRound = make(Round)
+
+	if !nextRound {
+		h.releaseHeldLocked()
+	}
+
+	metrics.RoundsSealed.WithLabelValues(h.server, reason).Inc()
+
+	if h.quarantinePath != "" {
+		round = h.validateRound(round)
+	}
+
+	// Deferred artifacts are uploaded on their own, the manifest and notifications
+	// only cover the ones uploaded now.
+	h.deferScheduled(round, nextRound)
+	if len(round.Usable()) == 0 {
 		return
 	}
 
-	go func(round Round) {
-		if h.notifier != nil {
-			err = h.notifier.Send(h.ctx, round)
-			if err != nil {
-				slog.Error("failed to send notification", "err", err, "op", "Handler.endCurrentRound")
-			}
+	if h.manifestDir != "" {
+		manifest, err := h.writeManifest(round)
+		if err != nil {
+			slog.Error("failed to write manifest", "err", err, "op", "Handler.endCurrentRound")
+		} else {
+			round[config.ArtifactTypeManifest] = manifest
 		}
+	}
+
+	// The trackers get their own copy, round is filled with the upload results below.
+	sealedRound := maps.Clone(round)
+	sealed := trackAll(h.ctx, h.notifiers, func(ctx context.Context, t RoundTracker) error {
+		return t.RoundSealed(ctx, sealedRound)
+	})
 
-		h.cleanupArtifacts(round)
-	}(h.currentRound)
+	usable := round.Usable()
+	results := h.uploader.Upload(h.ctx, usable)
 
-	h.currentRound = make(Round)
+	uploaded := make(Round, len(usable))
+	failed := make(Round)
+	for typ, artifact := range usable {
+		result, ok := results[typ]
+		if !ok || result.Err != nil {
+			failed[typ] = artifact
+			continue
+		}
+
+		artifact.RemotePath = result.RemotePath
+		artifact.URL = result.URL
+		round[typ] = artifact
+		uploaded[typ] = artifact
+	}
+
+	if len(failed) > 0 {
+		err := results.Err(failed)
+		slog.Error("failed to upload round", "err", err, "failed", len(failed), "uploaded", len(uploaded), "op", "Handler.endCurrentRound")
+		h.alert(Alert{Kind: AlertUploadFailed, Round: failed, Err: err})
+		go func() {
+			// The placeholder must be posted before it can be marked as failed.
+			sealed.Wait()
+			trackAll(h.ctx, h.notifiers, func(ctx context.Context, t RoundTracker) error {
+				return t.RoundFailed(ctx, round, err)
+			})
+
+			// Only the failed artifacts are kept for a retry.
+			h.cleanupArtifacts(uploaded)
+			h.backupFailedUploads(failed)
+			h.checkBacklog()
+		}()
+		return
+	}
+
+	go func() {
+		// Let placeholders be posted before they get replaced.
+		sealed.Wait()
+		notifyAll(h.ctx, h.server, h.notifiers, round)
+		h.cleanupArtifacts(uploaded)
+	}()
 }
 
 func (h *Handler) backupFailedUploads(round Round) {
 	log := slog.With("op", "Handler.backupFailedUploads")
 
 	for _, artifact := range round {
+		if artifact.Type == config.ArtifactTypeManifest {
+			// Only artifacts are backed up, the manifest describes a single upload attempt.
+			if err := os.Remove(artifact.Path); err != nil {
+				log.Error("failed to remove manifest", "path", artifact.Path, "err", err)
+			}
+			continue
+		}
+
 		newPath := filepath.Join(h.failedUploadPath, artifact.Type.String(), filepath.Base(artifact.Path))
 		if err := move(artifact.Path, newPath); err != nil {
 			log.Error("failed to move file", "src", artifact.Path, "dst", newPath, "err", err)
@@ -287,34 +464,50 @@ func move(source, destination string) error {
 }
 
 func moveCrossDevice(source, destination string) error {
-	src, err := os.Open(source)
-	if err != nil {
+	if err := copyFile(source, destination); err != nil {
 		return err
 	}
-	dst, err := os.Create(destination)
+	return os.Remove(source)
+}
+
+// copyFile copies source to destination with the same mode and syncs it to disk.
+func copyFile(source, destination string) error {
+	src, err := os.Open(source)
 	if err != nil {
-		src.Close()
 		return err
 	}
-	_, err = io.Copy(dst, src)
-	src.Close()
-	dst.Close()
+	defer src.Close()
+
+	fi, err := src.Stat()
 	if err != nil {
 		return err
 	}
-	fi, err := src.Stat()
+
+	return writeFile(destination, src, fi.Mode())
+}
+
+// writeFile writes r to path with mode and syncs it to disk, the file is removed on failure.
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
-		if err := os.Remove(destination); err != nil {
-			return err
-		}
 		return err
 	}
-	err = os.Chmod(destination, fi.Mode())
+
+	_, err = io.Copy(dst, r)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// OpenFile applies the umask.
+		err = os.Chmod(path, mode)
+	}
 	if err != nil {
-		if err := os.Remove(destination); err != nil {
-			return err
-		}
+		os.Remove(path)
 		return err
 	}
-	return os.Remove(source)
+
+	return nil
 }
@@ -1,11 +1,16 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
 	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -111,7 +116,7 @@ func TestHandler(t *testing.T) {
 
 				uploader := NewMockUploader(ctrl)
 				for _, round := range test.expectedRounds {
-					uploader.EXPECT().Upload(round)
+					uploader.EXPECT().Upload(gomock.Any(), round).Return(uploadedAll(round))
 				}
 
 				failedDir := t.TempDir()
@@ -139,7 +144,7 @@ func TestHandler(t *testing.T) {
 						artifact.Path = filepath.Join(dir, artifact.Path)
 						round[typ] = artifact
 					}
-					uploader.EXPECT().Upload(round)
+					uploader.EXPECT().Upload(gomock.Any(), round).Return(uploadedAll(round))
 				}
 
 				for _, file := range test.files {
@@ -175,3 +180,388 @@ func prepareRound(artifacts map[config.ArtifactType]string) Round {
 
 	return round
 }
+
+// uploadedAll reports every artifact of the round as uploaded.
+func uploadedAll(round Round) UploadResults {
+	results := make(UploadResults, len(round))
+	for typ := range round {
+		results[typ] = UploadResult{}
+	}
+
+	return results
+}
+
+// uploadsAll uploads every artifact of the round, for DoAndReturn.
+func uploadsAll(_ context.Context, round Round) UploadResults {
+	return uploadedAll(round)
+}
+
+func TestHandlerNotifiers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	bf2DemoDir := filepath.Join(dir, "bf2demos")
+	require.NoError(t, os.MkdirAll(bf2DemoDir, 0755))
+
+	file1 := filepath.Join(bf2DemoDir, "file1")
+	require.NoError(t, os.WriteFile(file1, []byte("test"), 0644))
+
+	round := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: file1,
+	})
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), round).Return(uploadedAll(round))
+
+	failing := NewMockNotifier(ctrl)
+	failing.EXPECT().Send(gomock.Any(), round).Return(errors.New("boom")).Times(2)
+
+	rejected := NewMockNotifier(ctrl)
+	rejected.EXPECT().Send(gomock.Any(), round).Return(Permanent(errors.New("rejected")))
+
+	succeeding := NewMockNotifier(ctrl)
+	succeeding.EXPECT().Send(gomock.Any(), round)
+
+	notifiers := []NotifierSpec{
+		{Name: "failing", Notifier: failing, Retries: 1, RetryDelay: time.Millisecond},
+		{Name: "rejected", Notifier: rejected, Retries: 3, RetryDelay: time.Millisecond},
+		{Name: "succeeding", Notifier: succeeding},
+	}
+
+	handler, err := NewHandler(uploader, notifiers, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: config.Location{Location: bf2DemoDir},
+	}, 0, t.TempDir())
+	require.NoError(t, err)
+
+	handler.OnFileCreate(file1)
+	handler.OnFileCreate(filepath.Join(bf2DemoDir, "file2"))
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(file1)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+}
+
+// trackingNotifier records the rounds it is given as a RoundTracker, posting the
+// placeholder takes sealDelay.
+type trackingNotifier struct {
+	sealDelay time.Duration
+	sealed    chan Round
+	failed    chan Round
+	sent      chan Round
+}
+
+func (n *trackingNotifier) Send(_ context.Context, round Round) error {
+	n.sent <- round
+	return nil
+}
+
+func (n *trackingNotifier) RoundSealed(_ context.Context, round Round) error {
+	// Walk the round like a placeholder does while the upload is running.
+	_ = round.Key()
+	time.Sleep(n.sealDelay)
+	n.sealed <- round
+	return nil
+}
+
+func (n *trackingNotifier) RoundFailed(_ context.Context, round Round, _ error) error {
+	n.failed <- round
+	return nil
+}
+
+func TestHandlerRoundTracker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	bf2DemoDir := filepath.Join(dir, "bf2demos")
+	require.NoError(t, os.MkdirAll(bf2DemoDir, 0755))
+
+	file1 := filepath.Join(bf2DemoDir, "file1")
+	require.NoError(t, os.WriteFile(file1, []byte("test"), 0644))
+
+	round := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: file1,
+	})
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), round).Return(UploadResults{
+		config.ArtifactTypeBF2Demo: {RemotePath: "remote/file1"},
+	})
+
+	tracker := &trackingNotifier{
+		sealed: make(chan Round, 1),
+		sent:   make(chan Round, 1),
+	}
+
+	handler, err := NewHandler(uploader, []NotifierSpec{{Name: "tracker", Notifier: tracker}}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: config.Location{Location: bf2DemoDir},
+	}, 0, t.TempDir())
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(file1)
+	handler.OnFileCreate(filepath.Join(bf2DemoDir, "file2"))
+
+	sealed := <-tracker.sealed
+	require.Empty(t, sealed[config.ArtifactTypeBF2Demo].RemotePath)
+
+	sent := <-tracker.sent
+	require.Equal(t, "remote/file1", sent[config.ArtifactTypeBF2Demo].RemotePath)
+}
+
+func TestHandlerRoundTrackerFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	bf2DemoDir := filepath.Join(dir, "bf2demos")
+	require.NoError(t, os.MkdirAll(bf2DemoDir, 0755))
+
+	file1 := filepath.Join(bf2DemoDir, "file1")
+	require.NoError(t, os.WriteFile(file1, []byte("test"), 0644))
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), gomock.Any()).Return(UploadResults{
+		config.ArtifactTypeBF2Demo: {Err: errors.New("connection refused")},
+	})
+
+	tracker := &trackingNotifier{
+		sealDelay: 50 * time.Millisecond,
+		sealed:    make(chan Round, 1),
+		failed:    make(chan Round, 1),
+	}
+
+	handler, err := NewHandler(uploader, []NotifierSpec{{Name: "tracker", Notifier: tracker}}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: config.Location{Location: bf2DemoDir},
+	}, 0, t.TempDir())
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(file1)
+	handler.OnFileCreate(filepath.Join(bf2DemoDir, "file2"))
+
+	<-tracker.failed
+	require.Len(t, tracker.sealed, 1, "round failed before its placeholder was posted")
+}
+
+func TestHandlerAlerts(t *testing.T) {
+	t.Run("round timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		uploader := NewMockUploader(ctrl)
+		uploader.EXPECT().Upload(gomock.Any(), gomock.Any()).DoAndReturn(uploadsAll)
+
+		alerted := make(chan Alert, 1)
+		alerter := NewMockAlerter(ctrl)
+		alerter.EXPECT().Alert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, alert Alert) error {
+			alerted <- alert
+			return nil
+		})
+
+		handler, err := NewHandler(uploader, nil, config.ArtifactsConfig{
+			config.ArtifactTypeBF2Demo: config.Location{Location: "bf2demos"},
+			config.ArtifactTypePRDemo:  config.Location{Location: "prdemos"},
+			config.ArtifactTypeSummary: config.Location{Location: "json"},
+		}, 10*time.Millisecond, t.TempDir(), WithAlerters(0, alerter))
+		require.NoError(t, err)
+		defer handler.Close()
+
+		handler.OnFileCreate("bf2demos/file1")
+
+		alert := <-alerted
+		require.Equal(t, AlertRoundTimeout, alert.Kind)
+		require.Equal(t, []config.ArtifactType{config.ArtifactTypePRDemo, config.ArtifactTypeSummary}, alert.MissingTypes)
+	})
+
+	t.Run("upload failed and backlog", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		dir := t.TempDir()
+		for _, file := range []string{"file1", "file2"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte("test"), 0644))
+		}
+
+		uploadErr := errors.New("connection refused")
+		uploader := NewMockUploader(ctrl)
+		uploader.EXPECT().Upload(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, round Round) UploadResults {
+			return UploadResults{config.ArtifactTypeBF2Demo: {Err: uploadErr}}
+		}).Times(2)
+
+		alerted := make(chan Alert, 3)
+		alerter := NewMockAlerter(ctrl)
+		alerter.EXPECT().Alert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, alert Alert) error {
+			alerted <- alert
+			return nil
+		}).Times(3)
+
+		handler, err := NewHandler(uploader, nil, config.ArtifactsConfig{
+			config.ArtifactTypeBF2Demo: config.Location{Location: dir},
+		}, 0, t.TempDir(), WithAlerters(1, alerter))
+		require.NoError(t, err)
+		defer handler.Close()
+
+		handler.OnFileCreate(filepath.Join(dir, "file1"))
+		handler.OnFileCreate(filepath.Join(dir, "file2"))
+		require.Equal(t, AlertUploadFailed, (<-alerted).Kind)
+
+		handler.OnFileCreate(filepath.Join(dir, "file3"))
+
+		kinds := make([]AlertKind, 0, 2)
+		for range 2 {
+			alert := <-alerted
+			if alert.Kind == AlertUploadFailed {
+				require.ErrorIs(t, alert.Err, uploadErr)
+			}
+			kinds = append(kinds, alert.Kind)
+		}
+		require.ElementsMatch(t, []AlertKind{AlertUploadFailed, AlertBacklog}, kinds)
+	})
+}
+
+func TestHandlerValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	files := map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: filepath.Join(dir, "bf2demos", "file1"),
+		config.ArtifactTypePRDemo:  filepath.Join(dir, "prdemos", "file1"),
+		config.ArtifactTypeSummary: filepath.Join(dir, "json", "file1"),
+	}
+
+	artifactsConfig := make(config.ArtifactsConfig)
+	for typ, file := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		artifactsConfig[typ] = config.Location{Location: filepath.Dir(file)}
+	}
+
+	require.NoError(t, os.WriteFile(files[config.ArtifactTypeBF2Demo], []byte("test"), 0644))
+	require.NoError(t, os.WriteFile(files[config.ArtifactTypePRDemo], []byte("not a PR demo"), 0644))
+	require.NoError(t, os.WriteFile(files[config.ArtifactTypeSummary], []byte(`{"MapName": 5}`), 0644))
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), Round{
+		config.ArtifactTypeBF2Demo: {Path: files[config.ArtifactTypeBF2Demo], Type: config.ArtifactTypeBF2Demo, Status: ArtifactValid},
+	}).DoAndReturn(uploadsAll)
+
+	alerted := make(chan Alert, 1)
+	alerter := NewMockAlerter(ctrl)
+	alerter.EXPECT().Alert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, alert Alert) error {
+		alerted <- alert
+		return nil
+	})
+
+	quarantineDir := t.TempDir()
+
+	handler, err := NewHandler(uploader, nil, artifactsConfig, 0, t.TempDir(), WithValidation(quarantineDir), WithAlerters(0, alerter))
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(files[config.ArtifactTypeBF2Demo])
+	handler.OnFileCreate(files[config.ArtifactTypePRDemo])
+	handler.OnFileCreate(files[config.ArtifactTypeSummary])
+
+	alert := <-alerted
+	require.Equal(t, AlertInvalidArtifacts, alert.Kind)
+	require.Len(t, alert.Round, 2)
+	for _, artifact := range alert.Round {
+		require.Equal(t, ArtifactCorrupt, artifact.Status)
+		require.NotEmpty(t, artifact.Problem)
+	}
+
+	require.FileExists(t, filepath.Join(quarantineDir, "prdemo", "file1"))
+	require.FileExists(t, filepath.Join(quarantineDir, "summary", "file1"))
+}
+
+func TestHandlerManifest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bf2demos", "file1.bf2demo")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	artifactsConfig := config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: config.Location{Location: filepath.Dir(path), Compress: config.CompressionGzip},
+	}
+
+	manifestDir := filepath.Join(t.TempDir(), "manifests")
+	manifestPath := filepath.Join(manifestDir, "file1.manifest.json")
+
+	uploaded := make(chan Manifest, 1)
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, round Round) UploadResults {
+		require.Equal(t, Artifact{Path: manifestPath, Type: config.ArtifactTypeManifest}, round[config.ArtifactTypeManifest])
+
+		data, err := os.ReadFile(manifestPath)
+		require.NoError(t, err)
+
+		var manifest Manifest
+		require.NoError(t, json.Unmarshal(data, &manifest))
+		uploaded <- manifest
+		return uploadedAll(round)
+	})
+
+	handler, err := NewHandler(uploader, nil, artifactsConfig, 0, t.TempDir(), WithManifest("my-server", manifestDir))
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(path)
+	handler.mu.Lock()
+	handler.endCurrentRoundLocked(metrics.RoundComplete, false)
+	handler.mu.Unlock()
+
+	require.Equal(t, Manifest{
+		Server: "my-server",
+		Artifacts: []ManifestEntry{{
+			Name:        "file1.bf2demo.gz",
+			Type:        "bf2demo",
+			Size:        4,
+			SHA256:      "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Compression: config.CompressionGzip,
+		}},
+	}, <-uploaded)
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(manifestPath)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandlerPartialUpload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	files := map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: filepath.Join(dir, "bf2demos", "file1"),
+		config.ArtifactTypeSummary: filepath.Join(dir, "json", "file1"),
+	}
+
+	artifactsConfig := make(config.ArtifactsConfig)
+	for typ, file := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte("test"), 0644))
+		artifactsConfig[typ] = config.Location{Location: filepath.Dir(file)}
+	}
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), gomock.Any()).Return(UploadResults{
+		config.ArtifactTypeBF2Demo: {Err: errors.New("connection reset")},
+		config.ArtifactTypeSummary: {RemotePath: "json/file1"},
+	})
+
+	failedDir := t.TempDir()
+
+	handler, err := NewHandler(uploader, nil, artifactsConfig, 0, failedDir)
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(files[config.ArtifactTypeBF2Demo])
+	handler.OnFileCreate(files[config.ArtifactTypeSummary])
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(failedDir, "bf2demo", "file1"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoFileExists(t, files[config.ArtifactTypeSummary])
+	require.NoFileExists(t, filepath.Join(failedDir, "summary", "file1"))
+}
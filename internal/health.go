@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultHealthCheckInterval = time.Minute
+	defaultStallTimeout        = time.Hour
+)
+
+// Checker is implemented by uploaders that can tell whether their destination is reachable.
+type Checker interface {
+	Check() error
+}
+
+// Health reports the liveness and readiness of the mover on the admin listener.
+// Upload destinations are checked in the background, every interval, while
+// directories are checked on every request.
+type Health struct {
+	watcher  *Watcher
+	interval time.Duration
+	// stallTimeout is how long the watch loop may go without a heartbeat.
+	stallTimeout time.Duration
+	// discord reports whether the Discord session is connected, nil when no
+	// server posts to Discord.
+	discord func() bool
+
+	servers map[string]*serverHealth
+}
+
+type serverHealth struct {
+	checker Checker
+	dirs    []string
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+func NewHealth(watcher *Watcher, interval, stallTimeout time.Duration) *Health {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	if stallTimeout <= 0 {
+		stallTimeout = defaultStallTimeout
+	}
+
+	return &Health{
+		watcher:      watcher,
+		interval:     interval,
+		stallTimeout: stallTimeout,
+		servers:      make(map[string]*serverHealth),
+	}
+}
+
+// RequireDiscord makes readiness depend on connected, for servers posting to Discord.
+func (h *Health) RequireDiscord(connected func() bool) {
+	h.discord = connected
+}
+
+// AddServer checks the destination of server with checker and requires the mover
+// to be able to write to dirs.
+func (h *Health) AddServer(server string, checker Checker, dirs []string) {
+	h.servers[server] = &serverHealth{
+		checker: checker,
+		dirs:    dirs,
+	}
+}
+
+// Run checks the destinations right away and then every interval until ctx is done.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.checkDestinations()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Health) checkDestinations() {
+	var wg sync.WaitGroup
+
+	for name, server := range h.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := server.checker.Check()
+			if err != nil {
+				slog.Warn("upload destination unreachable", "server", name, "err", err, "op", "Health.checkDestinations")
+			}
+
+			server.mu.Lock()
+			server.checkedAt = time.Now()
+			server.err = err
+			server.mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Liveness is the body of /healthz.
+type Liveness struct {
+	Alive   bool   `json:"alive"`
+	Watcher string `json:"watcher"`
+	// Heartbeat is zero until the watch loop started.
+	Heartbeat time.Time `json:"heartbeat,omitzero"`
+}
+
+// Readiness is the body of /readyz.
+type Readiness struct {
+	Ready   bool   `json:"ready"`
+	Watcher string `json:"watcher"`
+	// Discord is omitted when no server posts to Discord.
+	Discord *bool                      `json:"discord,omitempty"`
+	Servers map[string]ServerReadiness `json:"servers"`
+}
+
+type ServerReadiness struct {
+	Ready       bool              `json:"ready"`
+	Destination DestinationStatus `json:"destination"`
+	// Dirs maps the directories that cannot be written to the reason.
+	Dirs map[string]string `json:"dirs,omitempty"`
+}
+
+type DestinationStatus struct {
+	Reachable bool `json:"reachable"`
+	// CheckedAt is zero until the first check finished.
+	CheckedAt time.Time `json:"checkedAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Liveness fails once the watch loop has stopped or has not had a heartbeat for
+// the stall timeout, e.g. when it is stuck handling a file. A long upload of old
+// files at start up, before the loop runs, does not get the process restarted.
+func (h *Health) Liveness() Liveness {
+	state := h.watcher.State()
+	heartbeat := h.watcher.Heartbeat()
+
+	alive := state != WatcherStopped
+	if state == WatcherRunning && time.Since(heartbeat) > h.stallTimeout {
+		alive = false
+	}
+
+	return Liveness{
+		Alive:     alive,
+		Watcher:   state.String(),
+		Heartbeat: heartbeat,
+	}
+}
+
+// Readiness requires the watch loop to run, the Discord session to be connected
+// and, for every server, a successful destination check within the last two
+// intervals and writable directories.
+func (h *Health) Readiness() Readiness {
+	state := h.watcher.State()
+
+	r := Readiness{
+		Ready:   state == WatcherRunning,
+		Watcher: state.String(),
+		Servers: make(map[string]ServerReadiness, len(h.servers)),
+	}
+
+	if h.discord != nil {
+		connected := h.discord()
+		r.Discord = &connected
+		r.Ready = r.Ready && connected
+	}
+
+	for name, server := range h.servers {
+		sr := server.readiness(2 * h.interval)
+		r.Servers[name] = sr
+		r.Ready = r.Ready && sr.Ready
+	}
+
+	return r
+}
+
+func (s *serverHealth) readiness(maxAge time.Duration) ServerReadiness {
+	s.mu.Lock()
+	checkedAt, err := s.checkedAt, s.err
+	s.mu.Unlock()
+
+	dest := DestinationStatus{CheckedAt: checkedAt}
+
+	switch {
+	case checkedAt.IsZero():
+		dest.Error = "not checked yet"
+	case err != nil:
+		dest.Error = err.Error()
+	case time.Since(checkedAt) > maxAge:
+		dest.Error = "last check is too old"
+	default:
+		dest.Reachable = true
+	}
+
+	sr := ServerReadiness{
+		Ready:       dest.Reachable,
+		Destination: dest,
+	}
+
+	for _, dir := range s.dirs {
+		if err := checkWritable(dir); err != nil {
+			if sr.Dirs == nil {
+				sr.Dirs = make(map[string]string)
+			}
+			sr.Dirs[dir] = err.Error()
+			sr.Ready = false
+		}
+	}
+
+	return sr
+}
+
+// HandleHealthz serves the liveness, 503 Service Unavailable once it fails.
+func (h *Health) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	l := h.Liveness()
+	writeHealth(w, l.Alive, l)
+}
+
+// HandleReadyz serves the readiness, 503 Service Unavailable when not ready.
+func (h *Health) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	rd := h.Readiness()
+	writeHealth(w, rd.Ready, rd)
+}
+
+func writeHealth(w http.ResponseWriter, ok bool, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write health response", "err", err, "op", "writeHealth")
+	}
+}
+
+// checkWritable tells whether the process can create files in dir, without creating
+// one: watched directories would pick it up as an artifact.
+func checkWritable(dir string) error {
+	if err := unix.Access(dir, unix.W_OK|unix.X_OK); err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+
+	return nil
+}
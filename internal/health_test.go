@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type checkerFunc func() error
+
+func (f checkerFunc) Check() error {
+	return f()
+}
+
+func TestHealth(t *testing.T) {
+	watched := t.TempDir()
+
+	w := NewWatcher()
+	w.Register([]string{watched}, nil)
+
+	health := NewHealth(w, time.Minute, time.Hour)
+	health.AddServer("reachable", checkerFunc(func() error { return nil }), []string{watched})
+	health.AddServer("unreachable", checkerFunc(func() error { return errors.New("connection refused") }), []string{watched})
+	health.AddServer("missing-dir", checkerFunc(func() error { return nil }), []string{filepath.Join(watched, "missing")})
+
+	connected := false
+	health.RequireDiscord(func() bool { return connected })
+
+	readyz := func() (int, Readiness) {
+		rec := httptest.NewRecorder()
+		health.HandleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var r Readiness
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&r))
+
+		return rec.Code, r
+	}
+
+	t.Run("starting", func(t *testing.T) {
+		l := health.Liveness()
+		require.True(t, l.Alive)
+		require.Equal(t, "starting", l.Watcher)
+
+		code, r := readyz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "not checked yet", r.Servers["reachable"].Destination.Error)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Watch(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return w.State() == WatcherRunning }, time.Second, 10*time.Millisecond)
+
+	health.checkDestinations()
+
+	t.Run("servers", func(t *testing.T) {
+		connected = true
+
+		code, r := readyz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.False(t, r.Ready)
+		require.Equal(t, "running", r.Watcher)
+		require.True(t, *r.Discord)
+
+		require.True(t, r.Servers["reachable"].Ready)
+		require.True(t, r.Servers["reachable"].Destination.Reachable)
+
+		require.False(t, r.Servers["unreachable"].Ready)
+		require.Equal(t, "connection refused", r.Servers["unreachable"].Destination.Error)
+
+		require.False(t, r.Servers["missing-dir"].Ready)
+		require.True(t, r.Servers["missing-dir"].Destination.Reachable)
+		require.Contains(t, r.Servers["missing-dir"].Dirs, filepath.Join(watched, "missing"))
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		delete(health.servers, "unreachable")
+		delete(health.servers, "missing-dir")
+
+		code, r := readyz()
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, r.Ready)
+
+		connected = false
+
+		code, _ = readyz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	healthz := func() (int, Liveness) {
+		rec := httptest.NewRecorder()
+		health.HandleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		var l Liveness
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&l))
+
+		return rec.Code, l
+	}
+
+	t.Run("alive", func(t *testing.T) {
+		code, l := healthz()
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, l.Alive)
+		require.WithinDuration(t, time.Now(), l.Heartbeat, time.Second)
+	})
+
+	t.Run("stalled", func(t *testing.T) {
+		// As if the loop was stuck handling a file since.
+		w.heartbeat.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+
+		code, l := healthz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.False(t, l.Alive)
+		require.Equal(t, "running", l.Watcher)
+	})
+
+	cancel()
+	<-done
+
+	t.Run("stopped", func(t *testing.T) {
+		code, l := healthz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.False(t, l.Alive)
+		require.Equal(t, "stopped", l.Watcher)
+	})
+}
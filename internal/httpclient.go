@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+const (
+	defaultConnectTimeout = 30 * time.Second
+	proxyNone             = "none"
+)
+
+// newHTTPClient builds the client used to talk to an upload server.
+func newHTTPClient(conf config.HTTPClient) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	connectTimeout := conf.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = conf.ResponseTimeout
+	transport.TLSClientConfig = tlsConfig
+
+	switch conf.Proxy {
+	case "":
+		transport.Proxy = http.ProxyFromEnvironment
+	case proxyNone:
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(conf.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if conf.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   conf.Timeout,
+	}, nil
+}
+
+func newTLSConfig(conf config.HTTPSTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: conf.ServerName,
+	}
+
+	if conf.CAFile != "" {
+		pem, err := os.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", conf.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		if conf.CertFile == "" || conf.KeyFile == "" {
+			return nil, errors.New("both certFile and keyFile are required for a client certificate")
+		}
+
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// setAuth adds the credentials of auth to req.
+func setAuth(req *http.Request, auth config.HTTPSAuth) {
+	for k, v := range auth.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if auth.Bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.Bearer)
+	}
+
+	if auth.Basic != nil {
+		req.SetBasicAuth(auth.Basic.Username, auth.Basic.Password)
+	}
+}
+
+// checkHTTP tells whether the server at uri answers with a HEAD request. Any response
+// but a 5xx or rejected credentials counts, upload endpoints often refuse HEAD.
+func checkHTTP(client *http.Client, uri string, auth config.HTTPSAuth) error {
+	req, err := http.NewRequest(http.MethodHead, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	setAuth(req, auth)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("check failed with status: %s", resp.Status)
+	}
+
+	return nil
+}
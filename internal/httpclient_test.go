@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	clientDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "artifacts-mover"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "artifacts-mover"}}, &clientKey.PublicKey, clientKey)
+	require.NoError(t, err)
+
+	clientCert, err := x509.ParseCertificate(clientDER)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+
+	tests := []struct {
+		name string
+		tls  config.HTTPSTLS
+		ok   bool
+	}{
+		{name: "unknown CA", tls: config.HTTPSTLS{CertFile: certFile, KeyFile: keyFile}},
+		{name: "no client certificate", tls: config.HTTPSTLS{CAFile: caFile}},
+		{name: "wrong server name", tls: config.HTTPSTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "archive.local"}},
+		{name: "mutual TLS", tls: config.HTTPSTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, ok: true},
+		{name: "server name", tls: config.HTTPSTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newHTTPClient(config.HTTPClient{TLS: tt.tls, Timeout: 5 * time.Second, Proxy: proxyNone})
+			require.NoError(t, err)
+
+			resp, err := client.Get(srv.URL)
+			if !tt.ok {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+)
+
+// Manifest lists the artifacts of a round with their checksums, it is uploaded along with them.
+type Manifest struct {
+	Server    string          `json:"server"`
+	StartTime time.Time       `json:"startTime,omitzero"`
+	EndTime   time.Time       `json:"endTime,omitzero"`
+	Artifacts []ManifestEntry `json:"artifacts"`
+}
+
+// ManifestEntry describes one artifact. Size and SHA256 are those of the file on disk,
+// Name is the remote file name, which has a suffix when the artifact is compressed.
+type ManifestEntry struct {
+	Name        string             `json:"name"`
+	Type        string             `json:"type"`
+	Size        int64              `json:"size"`
+	SHA256      string             `json:"sha256"`
+	Compression config.Compression `json:"compression,omitempty"`
+}
+
+// WithManifest uploads a manifest with every round, written to dir until it is uploaded.
+func WithManifest(server, dir string) HandlerOption {
+	return func(h *Handler) {
+		h.server = server
+		h.manifestDir = dir
+	}
+}
+
+func (h *Handler) newManifest(round Round) (*Manifest, error) {
+	manifest := &Manifest{
+		Server:    h.server,
+		Artifacts: make([]ManifestEntry, 0, len(round)),
+	}
+
+	usable := round.Usable()
+
+	s, _, err := summary.Resolve(usable[config.ArtifactTypeSummary].Path, usable[config.ArtifactTypePRDemo].Path)
+	if err != nil {
+		slog.Warn("failed to resolve round times for manifest", "err", err, "op", "Handler.newManifest")
+	} else if s != nil {
+		manifest.StartTime = time.Unix(s.StartTime, 0).UTC()
+		manifest.EndTime = time.Unix(s.EndTime, 0).UTC()
+	}
+
+	for typ, artifact := range usable {
+		size, sum, err := hashArtifact(artifact.Path, config.Location{})
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Artifacts = append(manifest.Artifacts, ManifestEntry{
+			Name:        h.artifactsConfig.RemoteName(typ, artifact.Path),
+			Type:        typ.String(),
+			Size:        size,
+			SHA256:      sum,
+			Compression: h.artifactsConfig[typ].Compress,
+		})
+	}
+
+	slices.SortFunc(manifest.Artifacts, func(a, b ManifestEntry) int {
+		return strings.Compare(a.Type, b.Type)
+	})
+
+	return manifest, nil
+}
+
+// writeManifest writes the manifest of the round to the manifest directory and
+// returns it as an artifact.
+func (h *Handler) writeManifest(round Round) (Artifact, error) {
+	manifest, err := h.newManifest(round)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	path := filepath.Join(h.manifestDir, manifestName(round))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return Artifact{}, err
+	}
+
+	return Artifact{Path: path, Type: config.ArtifactTypeManifest}, nil
+}
+
+// manifestName names the manifest after the first artifact of the round.
+func manifestName(round Round) string {
+	for _, typ := range []config.ArtifactType{config.ArtifactTypeBF2Demo, config.ArtifactTypePRDemo, config.ArtifactTypeSummary} {
+		if artifact, ok := round[typ]; ok {
+			name := filepath.Base(artifact.Path)
+			return strings.TrimSuffix(name, filepath.Ext(name)) + ".manifest.json"
+		}
+	}
+
+	return fmt.Sprintf("%d.manifest.json", time.Now().Unix())
+}
+
+// hashArtifact returns the size and SHA-256 of the artifact at path as it is uploaded to loc.
+func hashArtifact(path string, loc config.Location) (int64, string, error) {
+	file, err := openArtifact(path, loc)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumMismatch is returned by uploaders when the remote file differs from the uploaded one.
+func checksumMismatch(path, expected, actual string) error {
+	return fmt.Errorf("checksum mismatch for %s: uploaded %s, remote has %s", path, expected, actual)
+}
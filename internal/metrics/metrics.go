@@ -0,0 +1,111 @@
+// Package metrics holds the Prometheus metrics of the mover, served on the admin listener.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "artifacts_mover"
+
+// Round seal reasons.
+const (
+	RoundComplete = "complete"
+	RoundTimeout  = "timeout"
+)
+
+// Registry holds the mover metrics together with the Go runtime and process ones.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	FilesDetected = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "files_detected_total",
+		Help:      "Artifacts picked up from the watched directories.",
+	}, []string{"server", "type"})
+
+	RoundsSealed = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rounds_sealed_total",
+		Help:      "Rounds sealed for upload, by reason: complete or timeout.",
+	}, []string{"server", "reason"})
+
+	UploadAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_attempts_total",
+		Help:      "Artifact uploads attempted, including skipped ones.",
+	}, []string{"server", "destination", "type"})
+
+	UploadFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_failures_total",
+		Help:      "Artifact uploads that failed after all retries.",
+	}, []string{"server", "destination", "type"})
+
+	UploadBytes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes sent by successful uploads, after compression.",
+	}, []string{"server", "destination", "type"})
+
+	UploadDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Time spent uploading an artifact, retries included.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+	}, []string{"server", "destination", "type"})
+
+	NotificationsSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Notifications attempted, retries included.",
+	}, []string{"server", "notifier"})
+
+	NotificationFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_failures_total",
+		Help:      "Notification attempts that failed.",
+	}, []string{"server", "notifier"})
+
+	DiscordRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discord_requests_total",
+		Help:      "Messages sent or edited through the Discord API.",
+	}, []string{"method"})
+
+	DiscordFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discord_request_failures_total",
+		Help:      "Discord API requests that failed.",
+	}, []string{"method"})
+
+	FailedBacklog = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "failed_uploads",
+		Help:      "Files waiting in the failed upload directory.",
+	}, []string{"server"})
+
+	DeferredUploads = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deferred_uploads",
+		Help:      "Artifacts held for the round in progress or waiting for their upload window.",
+	}, []string{"server"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
+const (
+	defaultNotifierTimeout    = 2 * time.Minute
+	defaultNotifierRetryDelay = 10 * time.Second
+)
+
+// RoundTracker is implemented by notifiers that announce a round as soon as it is
+// sealed, before its upload finishes, and update the announcement once the upload
+// failed. A successful upload is reported through Notifier.Send.
+type RoundTracker interface {
+	RoundSealed(context.Context, Round) error
+	RoundFailed(context.Context, Round, error) error
+}
+
+// NotifierSpec is a Notifier together with its delivery options.
+type NotifierSpec struct {
+	Name       string
+	Notifier   Notifier
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// PermanentError is returned by notifiers for failures retrying cannot fix, e.g. a
+// request rejected by the receiver. It stops the retries of NotifierSpec.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// notifyAll runs every notifier concurrently and waits for all of them.
+// A failing notifier is logged and does not affect the others.
+func notifyAll(ctx context.Context, server string, notifiers []NotifierSpec, round Round) {
+	var wg sync.WaitGroup
+
+	for _, spec := range notifiers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := spec.send(ctx, server, round); err != nil {
+				slog.Error("failed to send notification", "err", err, "notifier", spec.Name, "op", "notifyAll")
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// trackAll calls fn for every notifier implementing RoundTracker without waiting for them.
+// The returned WaitGroup is done once all calls returned.
+func trackAll(ctx context.Context, notifiers []NotifierSpec, fn func(context.Context, RoundTracker) error) *sync.WaitGroup {
+	var wg sync.WaitGroup
+
+	for _, spec := range notifiers {
+		tracker, ok := spec.Notifier.(RoundTracker)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			timeout := spec.Timeout
+			if timeout == 0 {
+				timeout = defaultNotifierTimeout
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			if err := fn(ctx, tracker); err != nil {
+				slog.Error("failed to update round progress", "err", err, "notifier", spec.Name, "op", "trackAll")
+			}
+		}()
+	}
+
+	return &wg
+}
+
+// send delivers the round, retrying failed attempts. Every attempt is counted in
+// the notification metrics of server.
+func (s NotifierSpec) send(ctx context.Context, server string, round Round) error {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = defaultNotifierTimeout
+	}
+
+	retryDelay := s.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultNotifierRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := s.Notifier.Send(attemptCtx, round)
+		cancel()
+
+		metrics.NotificationsSent.WithLabelValues(server, s.Name).Inc()
+		if err == nil {
+			return nil
+		}
+
+		metrics.NotificationFailures.WithLabelValues(server, s.Name).Inc()
+
+		var permanent *PermanentError
+		if attempt >= s.Retries || errors.As(err, &permanent) {
+			return err
+		}
+
+		slog.Warn("notification failed, retrying", "err", err, "notifier", s.Name, "attempt", attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
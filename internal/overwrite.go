@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+// remoteFile is what an uploader knows about the destination of an artifact.
+type remoteFile struct {
+	Exists bool
+	// Size is -1 when unknown.
+	Size int64
+	// SHA256 is empty when unknown.
+	SHA256 string
+}
+
+// skipUpload reports whether the artifact at path, uploaded to loc, must not be
+// uploaded over remote according to the overwrite policy.
+func skipUpload(policy config.Overwrite, remote remoteFile, path string, loc config.Location) (bool, error) {
+	if !remote.Exists {
+		return false, nil
+	}
+
+	switch policy {
+	case config.OverwriteNever:
+		return true, nil
+	case config.OverwriteIfDifferent:
+		// The checksum of an encrypted file says nothing, only its size can be compared.
+		if remote.Size < 0 && (remote.SHA256 == "" || loc.Encrypt != nil) {
+			// Nothing to compare with, assume the remote file differs.
+			return false, nil
+		}
+
+		size, sum, err := hashArtifact(path, loc)
+		if err != nil {
+			return false, err
+		}
+
+		if remote.Size >= 0 && remote.Size != size {
+			return false, nil
+		}
+
+		// Encryption is not deterministic, encrypted files of the same artifact only share the size.
+		if remote.SHA256 != "" && loc.Encrypt == nil && !strings.EqualFold(remote.SHA256, sum) {
+			return false, nil
+		}
+
+		return true, nil
+	default:
+		return false, nil
+	}
+}
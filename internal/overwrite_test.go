@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipUpload(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	encrypted := config.Location{Encrypt: &config.Encryption{Recipients: []string{identity.Recipient().String()}}}
+
+	encryptedSize, _, err := hashArtifact(path, encrypted)
+	require.NoError(t, err)
+
+	// sha256 of "test"
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name   string
+		policy config.Overwrite
+		remote remoteFile
+		loc    config.Location
+		skip   bool
+	}{
+		{name: "missing", policy: config.OverwriteNever, remote: remoteFile{Size: -1}},
+		{name: "never, existing", policy: config.OverwriteNever, remote: remoteFile{Exists: true, Size: -1}, skip: true},
+		{name: "if-different, nothing known", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: -1}},
+		{name: "if-different, same checksum", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: -1, SHA256: sum}, skip: true},
+		{name: "if-different, other checksum", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: 4, SHA256: "00"}},
+		{name: "if-different, other size", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: 5, SHA256: sum}},
+		{name: "if-different, encrypted, only checksum", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: -1, SHA256: "00"}, loc: encrypted},
+		{name: "if-different, encrypted, same size", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: encryptedSize, SHA256: "00"}, loc: encrypted, skip: true},
+		{name: "if-different, encrypted, other size", policy: config.OverwriteIfDifferent, remote: remoteFile{Exists: true, Size: encryptedSize + 1, SHA256: "00"}, loc: encrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, err := skipUpload(tt.policy, tt.remote, path, tt.loc)
+			require.NoError(t, err)
+			require.Equal(t, tt.skip, skip)
+		})
+	}
+}
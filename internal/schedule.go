@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
+// window is a daily time window in minutes since midnight, local time.
+// A window ending before it starts spans midnight.
+type window struct {
+	start, end int
+}
+
+func (w window) contains(minute int) bool {
+	if w.start == w.end {
+		return true
+	}
+
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+
+	return minute >= w.start || minute < w.end
+}
+
+// schedule holds the windows in which an artifact type may be uploaded, an empty schedule allows any time.
+type schedule []window
+
+func newSchedule(conf *config.Schedule) (schedule, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	s := make(schedule, 0, len(conf.Hours))
+	for _, hours := range conf.Hours {
+		from, to, ok := strings.Cut(hours, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule window %q, expected HH:MM-HH:MM", hours)
+		}
+
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, window{start: start, end: end})
+	}
+
+	return s, nil
+}
+
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q: %w", clock, err)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// next returns now if it falls within a window, otherwise the start of the next window.
+func (s schedule) next(now time.Time) time.Time {
+	if len(s) == 0 {
+		return now
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+
+	var next time.Time
+	for _, w := range s {
+		if w.contains(minute) {
+			return now
+		}
+
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, w.start, 0, 0, now.Location())
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+
+	return next
+}
+
+// deferScheduled removes the artifacts that are outside of their upload windows
+// from round and uploads them once their window opens. When nextRound is being played,
+// the artifacts uploaded between rounds are held until it ends. Corrupt artifacts are
+// never uploaded and stay in round.
+func (h *Handler) deferScheduled(round Round, nextRound bool) {
+	now := time.Now()
+
+	for typ, artifact := range round {
+		if artifact.Status == ArtifactCorrupt {
+			continue
+		}
+
+		if nextRound && h.betweenRounds[typ] {
+			slog.Info("deferring upload until the round in progress ends", "path", artifact.Path, "op", "Handler.deferScheduled")
+
+			delete(round, typ)
+			h.held = append(h.held, artifact)
+			metrics.DeferredUploads.WithLabelValues(h.server).Inc()
+			continue
+		}
+
+		at := h.schedules[typ].next(now)
+		if !at.After(now) {
+			continue
+		}
+
+		slog.Info("deferring upload to the schedule window", "path", artifact.Path, "at", at, "op", "Handler.deferScheduled")
+
+		delete(round, typ)
+		metrics.DeferredUploads.WithLabelValues(h.server).Inc()
+		h.uploadAfter(artifact, at.Sub(now))
+	}
+}
+
+// releaseHeldLocked uploads the artifacts held for the round that ended, within their windows.
+func (h *Handler) releaseHeldLocked() {
+	now := time.Now()
+
+	for _, artifact := range h.held {
+		h.uploadAfter(artifact, h.schedules[artifact.Type].next(now).Sub(now))
+	}
+
+	h.held = nil
+}
+
+func (h *Handler) uploadAfter(artifact Artifact, d time.Duration) {
+	time.AfterFunc(d, func() {
+		metrics.DeferredUploads.WithLabelValues(h.server).Dec()
+
+		select {
+		case <-h.ctx.Done():
+			return
+		default:
+		}
+
+		h.uploadDeferred(artifact)
+	})
+}
+
+func (h *Handler) uploadDeferred(artifact Artifact) {
+	round := Round{artifact.Type: artifact}
+
+	if err := h.uploader.Upload(h.ctx, round).Err(round); err != nil {
+		slog.Error("failed to upload deferred artifact", "err", err, "path", artifact.Path, "op", "Handler.uploadDeferred")
+		h.alert(Alert{Kind: AlertUploadFailed, Round: round, Err: err})
+		h.backupFailedUploads(round)
+		h.checkBacklog()
+		return
+	}
+
+	h.cleanupArtifacts(round)
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestScheduleNext(t *testing.T) {
+	s, err := newSchedule(&config.Schedule{Hours: []string{"02:00-06:00", "22:30-01:00"}})
+	require.NoError(t, err)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		now  time.Time
+		next time.Time
+	}{
+		{now: at(3, 0), next: at(3, 0)},
+		{now: at(23, 0), next: at(23, 0)},
+		{now: at(0, 30), next: at(0, 30)},
+		{now: at(1, 0), next: at(2, 0)},
+		{now: at(6, 0), next: at(22, 30)},
+		{now: at(12, 15), next: at(22, 30)},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.next, s.next(tt.now), tt.now.Format("15:04"))
+	}
+
+	require.Equal(t, at(12, 0), schedule(nil).next(at(12, 0)))
+
+	_, err = newSchedule(&config.Schedule{Hours: []string{"22:00"}})
+	require.Error(t, err)
+}
+
+func TestHandlerSchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	files := map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: filepath.Join(dir, "bf2demos", "file1"),
+		config.ArtifactTypeSummary: filepath.Join(dir, "json", "file1"),
+	}
+
+	// A window that opens in an hour, so the battle recorder is deferred.
+	start := time.Now().Add(time.Hour)
+	window := start.Format("15:04") + "-" + start.Add(time.Hour).Format("15:04")
+
+	artifactsConfig := config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {Location: filepath.Dir(files[config.ArtifactTypeBF2Demo]), Schedule: &config.Schedule{Hours: []string{window}}},
+		config.ArtifactTypeSummary: {Location: filepath.Dir(files[config.ArtifactTypeSummary])},
+	}
+
+	summaryOnly := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypeSummary: files[config.ArtifactTypeSummary],
+	})
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), summaryOnly).Return(uploadedAll(summaryOnly))
+
+	tracker := &trackingNotifier{
+		sealed: make(chan Round, 1),
+		sent:   make(chan Round, 1),
+	}
+
+	handler, err := NewHandler(uploader, []NotifierSpec{{Name: "tracker", Notifier: tracker}}, artifactsConfig, 0, t.TempDir())
+	require.NoError(t, err)
+	defer handler.Close()
+
+	metrics.DeferredUploads.Reset()
+
+	handler.OnFileCreate(files[config.ArtifactTypeBF2Demo])
+	handler.OnFileCreate(files[config.ArtifactTypeSummary])
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.DeferredUploads.WithLabelValues(handler.server)))
+
+	// The deferred battle recorder is not announced with the round.
+	require.NotContains(t, <-tracker.sealed, config.ArtifactTypeBF2Demo)
+	require.NotContains(t, <-tracker.sent, config.ArtifactTypeBF2Demo)
+}
+
+func TestHandlerScheduleBetweenRounds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	dirs := map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: filepath.Join(dir, "bf2demos"),
+		config.ArtifactTypePRDemo:  filepath.Join(dir, "prdemos"),
+		config.ArtifactTypeSummary: filepath.Join(dir, "json"),
+	}
+
+	artifactsConfig := config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {Location: dirs[config.ArtifactTypeBF2Demo], Schedule: &config.Schedule{BetweenRounds: true}},
+		config.ArtifactTypePRDemo:  {Location: dirs[config.ArtifactTypePRDemo]},
+		config.ArtifactTypeSummary: {Location: dirs[config.ArtifactTypeSummary]},
+	}
+
+	file := func(typ config.ArtifactType, name string) string {
+		return filepath.Join(dirs[typ], name)
+	}
+
+	// The first round has no summary, it ends when the battle recorder of the next one is created.
+	firstRound := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypePRDemo: file(config.ArtifactTypePRDemo, "file1"),
+	})
+	secondRound := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: file(config.ArtifactTypeBF2Demo, "file2"),
+		config.ArtifactTypePRDemo:  file(config.ArtifactTypePRDemo, "file2"),
+		config.ArtifactTypeSummary: file(config.ArtifactTypeSummary, "file2"),
+	})
+	held := prepareRound(map[config.ArtifactType]string{
+		config.ArtifactTypeBF2Demo: file(config.ArtifactTypeBF2Demo, "file1"),
+	})
+
+	uploaded := make(chan Round, 1)
+
+	uploader := NewMockUploader(ctrl)
+	uploader.EXPECT().Upload(gomock.Any(), firstRound).Return(uploadedAll(firstRound))
+	uploader.EXPECT().Upload(gomock.Any(), secondRound).Return(uploadedAll(secondRound))
+	uploader.EXPECT().Upload(gomock.Any(), held).DoAndReturn(func(_ context.Context, round Round) UploadResults {
+		uploaded <- round
+		return uploadedAll(round)
+	})
+
+	handler, err := NewHandler(uploader, nil, artifactsConfig, 0, t.TempDir())
+	require.NoError(t, err)
+	defer handler.Close()
+
+	handler.OnFileCreate(file(config.ArtifactTypeBF2Demo, "file1"))
+	handler.OnFileCreate(file(config.ArtifactTypePRDemo, "file1"))
+	handler.OnFileCreate(file(config.ArtifactTypeBF2Demo, "file2"))
+
+	select {
+	case <-uploaded:
+		t.Fatal("battle recorder uploaded while the next round is played")
+	default:
+	}
+
+	handler.OnFileCreate(file(config.ArtifactTypePRDemo, "file2"))
+	handler.OnFileCreate(file(config.ArtifactTypeSummary, "file2"))
+
+	select {
+	case round := <-uploaded:
+		require.Equal(t, held, round)
+	case <-time.After(time.Second):
+		t.Fatal("held battle recorder not uploaded after the round ended")
+	}
+}
+
+func TestHandlerScheduleBetweenRoundsBF2DemoOnly(t *testing.T) {
+	_, err := NewHandler(nil, nil, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {Location: t.TempDir(), Schedule: &config.Schedule{BetweenRounds: true}},
+	}, 0, t.TempDir())
+	require.Error(t, err)
+}
@@ -0,0 +1,236 @@
+package summary
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/emilekm/go-prbf2/prdemo"
+)
+
+// TicketSample holds the tickets of both teams at Time since the start of the demo.
+type TicketSample struct {
+	Time  time.Duration
+	Team1 int
+	Team2 int
+}
+
+// Demo is what ReadDemo extracts from a PR demo.
+type Demo struct {
+	// Details is nil when the demo has no server details message.
+	Details *prdemo.ServerDetails
+
+	Team1Tickets int
+	Team2Tickets int
+	// Tickets has a sample for every ticket change, starting with the initial tickets.
+	Tickets []TicketSample
+	// Duration is the demo time at which the round ended.
+	Duration time.Duration
+	// Ended reports whether the demo reached the end of round message,
+	// a demo without it was cut short.
+	Ended bool
+	// Truncated reports whether the demo data stops in the middle of the compressed
+	// stream or of a message, e.g. when the server crashed while writing it.
+	Truncated bool
+	// Players holds the last known state of every player that joined, by name.
+	Players map[string]Player
+}
+
+type ticks struct {
+	Ticks uint8
+}
+
+// ReadDemo walks the PR demo up to the end of the round. A demo that is cut off is
+// read as far as it goes and returned with Truncated set, an error means the file is
+// not a PR demo at all.
+func ReadDemo(prDemoPath string) (*Demo, error) {
+	data, truncated, err := readDemoData(prDemoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := prdemo.NewDemoReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	demo := &Demo{
+		Players:   make(map[string]Player),
+		Truncated: truncated,
+	}
+
+	// connected maps the in-game IDs, which get reused, to player names.
+	connected := make(map[uint8]string)
+
+	var (
+		elapsed     time.Duration
+		timePerTick time.Duration
+	)
+
+	addSample := func() {
+		demo.Tickets = append(demo.Tickets, TicketSample{
+			Time:  elapsed,
+			Team1: demo.Team1Tickets,
+			Team2: demo.Team2Tickets,
+		})
+	}
+
+	messages := 0
+
+	for reader.Next() {
+		msg, err := reader.GetMessage()
+		if err != nil {
+			// The rest of the demo cannot be framed once a message is broken.
+			demo.Truncated = true
+			break
+		}
+
+		messages++
+
+		switch msg.Type {
+		case prdemo.ServerDetailsType:
+			var details prdemo.ServerDetails
+			if err := msg.Decode(&details); err != nil {
+				continue
+			}
+
+			demo.Details = &details
+			timePerTick = time.Duration(float64(details.DemoTimePerTick) * float64(time.Second))
+			demo.Team1Tickets = int(details.Tickets1)
+			demo.Team2Tickets = int(details.Tickets2)
+			addSample()
+		case prdemo.PlayerAddType:
+			var added prdemo.PlayersAdd
+			if err := msg.Decode(&added); err != nil {
+				continue
+			}
+
+			for _, p := range added {
+				connected[p.ID] = p.IGN
+				if _, ok := demo.Players[p.IGN]; !ok {
+					demo.Players[p.IGN] = Player{Name: p.IGN}
+				}
+			}
+		case prdemo.PlayerRemoveType:
+			var removed prdemo.PlayerRemove
+			if err := msg.Decode(&removed); err != nil {
+				continue
+			}
+
+			delete(connected, removed.ID)
+		case prdemo.PlayerUpdateType:
+			var updates prdemo.PlayersUpdate
+			if err := msg.Decode(&updates); err != nil {
+				continue
+			}
+
+			for _, update := range updates {
+				name, ok := connected[update.ID]
+				if !ok {
+					continue
+				}
+
+				p := demo.Players[name]
+				if update.Team != nil {
+					p.Team = int(*update.Team)
+				}
+				if update.Score != nil {
+					p.Score = int(*update.Score)
+				}
+				demo.Players[name] = p
+			}
+		case prdemo.TicksType:
+			var t ticks
+			if err := msg.Decode(&t); err != nil {
+				continue
+			}
+
+			elapsed += time.Duration(t.Ticks) * timePerTick
+		case prdemo.TicketsTeam1Type, prdemo.TicketsTeam2Type:
+			var ticketMsg prdemo.Tickets
+			if err := msg.Decode(&ticketMsg); err != nil {
+				continue
+			}
+
+			tickets := max(int(ticketMsg.Tickets), 0)
+
+			if msg.Type == prdemo.TicketsTeam1Type {
+				demo.Team1Tickets = tickets
+			} else {
+				demo.Team2Tickets = tickets
+			}
+
+			addSample()
+		case prdemo.RoundEndType:
+			demo.Duration = elapsed
+			demo.Ended = true
+			return demo, nil
+		}
+	}
+
+	if messages == 0 {
+		return nil, errors.New("no messages in PR demo")
+	}
+
+	demo.Duration = elapsed
+
+	return demo, nil
+}
+
+// readDemoData decompresses the demo, returning what could be read when the
+// compressed stream ends early.
+func readDemoData(path string) ([]byte, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	zr, err := zlib.NewReader(file)
+	if err != nil {
+		return nil, false, err
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return data, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, false, nil
+}
+
+// Summary derives a summary from the demo, for servers that do not write summary JSON.
+// It returns nil when the demo has no server details.
+func (d *Demo) Summary() *Summary {
+	if d.Details == nil {
+		return nil
+	}
+
+	s := &Summary{
+		MapName:      d.Details.Map.Name,
+		MapMode:      d.Details.Map.Gamemode,
+		MapLayer:     int(d.Details.Map.Layer),
+		Team1Name:    d.Details.OpforTeam,
+		Team2Name:    d.Details.BluforTeam,
+		Team1Tickets: d.Team1Tickets,
+		Team2Tickets: d.Team2Tickets,
+		StartTime:    int64(d.Details.StartTime),
+		EndTime:      int64(d.Details.StartTime) + int64(d.Duration.Seconds()),
+		Players:      make([]Player, 0, len(d.Players)),
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(d.Players)) {
+		s.Players = append(s.Players, d.Players[name])
+	}
+
+	return s
+}
@@ -0,0 +1,183 @@
+package summary
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emilekm/go-prbf2/prdemo"
+	"github.com/stretchr/testify/require"
+)
+
+// demoWriter builds zlib compressed PR demos for tests.
+type demoWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *demoWriter) message(typ prdemo.MessageType, fields ...any) {
+	var msg bytes.Buffer
+	msg.WriteByte(byte(typ))
+
+	for _, field := range fields {
+		if s, ok := field.(string); ok {
+			msg.WriteString(s)
+			msg.WriteByte(0)
+			continue
+		}
+
+		if err := binary.Write(&msg, binary.LittleEndian, field); err != nil {
+			panic(err)
+		}
+	}
+
+	_ = binary.Write(&w.buf, binary.LittleEndian, uint16(msg.Len()))
+	w.buf.Write(msg.Bytes())
+}
+
+func (w *demoWriter) serverDetails(mapName, mode string, layer uint8, team1, team2 string, tickets1, tickets2 uint16) {
+	w.message(prdemo.ServerDetailsType,
+		int32(3), float32(0.5), "127.0.0.1:16567", "Test Server", uint8(100),
+		uint16(14400), uint16(240), mapName, mode, layer,
+		team1, team2, uint32(1700000000), tickets1, tickets2, float32(2),
+	)
+}
+
+func (w *demoWriter) write(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tracker.PRdemo")
+
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+	_, err := zw.Write(w.buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	require.NoError(t, os.WriteFile(path, out.Bytes(), 0644))
+
+	return path
+}
+
+func TestReadDemo(t *testing.T) {
+	w := &demoWriter{}
+	w.serverDetails("muttrah_city_2", "gpm_cq", 64, "usa", "mec", 300, 250)
+	w.message(prdemo.PlayerAddType, uint8(1), "Alpha", "hash1", "1.1.1.1", uint8(2), "Bravo", "hash2", "2.2.2.2")
+	w.message(prdemo.PlayerUpdateType, uint16(1|16), uint8(1), int8(1), int16(10), uint16(1|16), uint8(2), int8(2), int16(5))
+	w.message(prdemo.TicksType, uint8(120))
+	w.message(prdemo.PlayerRemoveType, uint8(2))
+	w.message(prdemo.PlayerAddType, uint8(2), "Charlie", "hash3", "3.3.3.3")
+	w.message(prdemo.PlayerUpdateType, uint16(16), uint8(1), int16(25), uint16(1|16), uint8(2), int8(2), int16(3))
+	w.message(prdemo.TicketsTeam1Type, int16(280))
+	w.message(prdemo.TicksType, uint8(60))
+	w.message(prdemo.TicketsTeam2Type, int16(-5))
+	w.message(prdemo.RoundEndType)
+	w.message(prdemo.TicketsTeam1Type, int16(1))
+
+	demo, err := ReadDemo(w.write(t))
+	require.NoError(t, err)
+
+	require.NotNil(t, demo.Details)
+	require.Equal(t, "muttrah_city_2", demo.Details.Map.Name)
+	require.Equal(t, 280, demo.Team1Tickets)
+	require.Equal(t, 0, demo.Team2Tickets)
+	require.Equal(t, 90*time.Second, demo.Duration)
+	require.True(t, demo.Ended)
+	require.Equal(t, []TicketSample{
+		{Time: 0, Team1: 300, Team2: 250},
+		{Time: time.Minute, Team1: 280, Team2: 250},
+		{Time: 90 * time.Second, Team1: 280, Team2: 0},
+	}, demo.Tickets)
+
+	require.Equal(t, &Summary{
+		MapName:      "muttrah_city_2",
+		MapMode:      "gpm_cq",
+		MapLayer:     64,
+		Team1Name:    "mec",
+		Team2Name:    "usa",
+		Team1Tickets: 280,
+		Team2Tickets: 0,
+		StartTime:    1700000000,
+		EndTime:      1700000090,
+		Players: []Player{
+			{Name: "Alpha", Score: 25, Team: 1},
+			{Name: "Bravo", Score: 5, Team: 2},
+			{Name: "Charlie", Score: 3, Team: 2},
+		},
+	}, demo.Summary())
+}
+
+func TestResolve(t *testing.T) {
+	w := &demoWriter{}
+	w.serverDetails("muttrah_city_2", "gpm_cq", 64, "usa", "mec", 300, 250)
+	w.message(prdemo.TicketsTeam1Type, int16(42))
+	w.message(prdemo.RoundEndType)
+	demoPath := w.write(t)
+
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+	require.NoError(t, os.WriteFile(summaryPath, []byte(`{"MapName": "from_json", "Team1Tickets": 1, "Team2Tickets": 2}`), 0644))
+
+	t.Run("summary with PR demo tickets", func(t *testing.T) {
+		s, demo, err := Resolve(summaryPath, demoPath)
+		require.NoError(t, err)
+		require.NotNil(t, demo)
+		require.Equal(t, "from_json", s.MapName)
+		require.Equal(t, 42, s.Team1Tickets)
+		require.Equal(t, 250, s.Team2Tickets)
+	})
+
+	t.Run("PR demo only", func(t *testing.T) {
+		s, _, err := Resolve("", demoPath)
+		require.NoError(t, err)
+		require.Equal(t, "muttrah_city_2", s.MapName)
+		require.Equal(t, 42, s.Team1Tickets)
+	})
+
+	t.Run("unreadable PR demo", func(t *testing.T) {
+		s, demo, err := Resolve(summaryPath, summaryPath)
+		require.NoError(t, err)
+		require.Nil(t, demo)
+		require.Equal(t, 1, s.Team1Tickets)
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		s, demo, err := Resolve("", "")
+		require.NoError(t, err)
+		require.Nil(t, s)
+		require.Nil(t, demo)
+	})
+}
+
+func TestReadDemoTruncated(t *testing.T) {
+	w := &demoWriter{}
+	w.serverDetails("muttrah_city_2", "gpm_cq", 64, "usa", "mec", 300, 250)
+	for i := range 200 {
+		w.message(prdemo.TicksType, uint8(10))
+		w.message(prdemo.TicketsTeam1Type, int16(300-i))
+	}
+	w.message(prdemo.RoundEndType)
+	demoPath := w.write(t)
+
+	data, err := os.ReadFile(demoPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(demoPath, data[:len(data)/2], 0644))
+
+	demo, err := ReadDemo(demoPath)
+	require.NoError(t, err)
+	require.True(t, demo.Truncated)
+	require.False(t, demo.Ended)
+	require.NotNil(t, demo.Details)
+	require.Less(t, demo.Team1Tickets, 300)
+	require.Greater(t, demo.Duration, time.Duration(0))
+
+	t.Run("not a PR demo", func(t *testing.T) {
+		notDemo := filepath.Join(t.TempDir(), "tracker.PRdemo")
+		require.NoError(t, os.WriteFile(notDemo, []byte("not a demo"), 0644))
+
+		_, err := ReadDemo(notDemo)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package summary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type Player struct {
+	Name  string
+	Score int
+	// Team is 1 or 2, it is 0 when the summary does not record teams.
+	Team int
+}
+
+// Summary mirrors the round summary JSON written by the PR server.
+type Summary struct {
+	MapName  string
+	MapMode  string
+	MapLayer int
+
+	Team1Name    string
+	Team2Name    string
+	Team1Tickets int
+	Team2Tickets int
+
+	StartTime int64
+	EndTime   int64
+	Players   []Player
+}
+
+func FromFile(path string) (*Summary, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Summary
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Validate checks that the summary JSON decodes into Summary and describes a round.
+func Validate(path string) error {
+	s, err := FromFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case s.MapName == "":
+		return errors.New("missing MapName")
+	case s.MapMode == "":
+		return errors.New("missing MapMode")
+	case s.StartTime == 0 || s.EndTime == 0:
+		return errors.New("missing StartTime or EndTime")
+	case s.EndTime < s.StartTime:
+		return fmt.Errorf("EndTime %d before StartTime %d", s.EndTime, s.StartTime)
+	}
+
+	return nil
+}
+
+// Resolve builds the summary of a round from its summary JSON and PR demo, either
+// path may be empty. The PR demo provides the final tickets, and the whole summary
+// when there is no summary JSON. A PR demo that cannot be read is logged and ignored,
+// the returned summary is nil when there is nothing to build it from.
+func Resolve(summaryPath, prDemoPath string) (*Summary, *Demo, error) {
+	var demo *Demo
+
+	if prDemoPath != "" {
+		var err error
+		demo, err = ReadDemo(prDemoPath)
+		if err != nil {
+			slog.Warn("failed to read PR demo", "path", prDemoPath, "error", err)
+		}
+	}
+
+	if summaryPath == "" {
+		if demo == nil {
+			return nil, nil, nil
+		}
+
+		return demo.Summary(), demo, nil
+	}
+
+	s, err := FromFile(summaryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if demo != nil {
+		s.Team1Tickets = demo.Team1Tickets
+		s.Team2Tickets = demo.Team2Tickets
+	}
+
+	return s, demo, nil
+}
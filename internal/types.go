@@ -2,7 +2,27 @@ package internal
 
 import "github.com/emilekm/artifacts-mover/internal/config"
 
+type ArtifactStatus string
+
+const (
+	// ArtifactValid artifacts passed validation.
+	ArtifactValid ArtifactStatus = "valid"
+	// ArtifactTruncated artifacts can be read but are incomplete, e.g. a PR demo
+	// without the end of round. They are still uploaded.
+	ArtifactTruncated ArtifactStatus = "truncated"
+	// ArtifactCorrupt artifacts cannot be read, they are quarantined instead of uploaded.
+	ArtifactCorrupt ArtifactStatus = "corrupt"
+)
+
 type Artifact struct {
 	Path string
 	Type config.ArtifactType
+	// Status is empty when the artifact was not validated.
+	Status ArtifactStatus
+	// Problem describes why the artifact is truncated or corrupt.
+	Problem string
+	// RemotePath and URL are set once the artifact is uploaded, URL only when the
+	// upload server reports it.
+	RemotePath string
+	URL        string
 }
@@ -1,7 +1,129 @@
 package internal
 
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+)
+
 //go:generate go run go.uber.org/mock/mockgen -source=./uploader.go -destination=./uploader_mock.go -package=internal Uploader
 
 type Uploader interface {
-	Upload(Round) error
+	// Upload uploads the artifacts of the round, a failing artifact does not stop the others.
+	// Uploads still running when ctx is done fail.
+	Upload(context.Context, Round) UploadResults
+}
+
+// UploadResult is the outcome of uploading one artifact.
+type UploadResult struct {
+	// RemotePath is where the file was stored on the remote.
+	RemotePath string
+	// URL is the download URL reported by the remote, empty when it is not known.
+	URL string
+	// Bytes is the number of bytes sent, after compression.
+	Bytes    int64
+	Duration time.Duration
+	// Skipped is set when the file already existed on the remote and was not sent again.
+	Skipped bool
+	Err     error
+}
+
+type UploadResults map[config.ArtifactType]UploadResult
+
+type instrumentedUploader struct {
+	Uploader
+	server      string
+	destination string
+}
+
+// InstrumentUploader records the results of the uploads of server to destination,
+// e.g. "https", in the upload metrics.
+func InstrumentUploader(server, destination string, uploader Uploader) Uploader {
+	return &instrumentedUploader{
+		Uploader:    uploader,
+		server:      server,
+		destination: destination,
+	}
+}
+
+func (u *instrumentedUploader) Upload(ctx context.Context, round Round) UploadResults {
+	results := u.Uploader.Upload(ctx, round)
+
+	for typ := range round {
+		labels := []string{u.server, u.destination, typ.String()}
+
+		metrics.UploadAttempts.WithLabelValues(labels...).Inc()
+
+		result, ok := results[typ]
+		if !ok || result.Err != nil {
+			metrics.UploadFailures.WithLabelValues(labels...).Inc()
+			continue
+		}
+
+		metrics.UploadBytes.WithLabelValues(labels...).Add(float64(result.Bytes))
+		metrics.UploadDuration.WithLabelValues(labels...).Observe(result.Duration.Seconds())
+	}
+
+	return results
+}
+
+// Err joins the errors of the failed artifacts, an artifact of round without
+// a result is considered failed.
+func (r UploadResults) Err(round Round) error {
+	var errs []error
+	for typ := range round {
+		result, ok := r[typ]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: not uploaded", typ))
+		} else if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", typ, result.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// partialName is the name uploaders write a file under until it is complete.
+func partialName(name string) string {
+	return "." + name + ".partial"
+}
+
+// digest counts and hashes the data written to it.
+type digest struct {
+	hash.Hash
+	n int64
+}
+
+func newDigest() *digest {
+	return &digest{Hash: sha256.New()}
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	d.n += int64(len(p))
+	return d.Hash.Write(p)
+}
+
+// Hex returns the SHA-256 of the data written so far.
+func (d *digest) Hex() string {
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+// sleepContext waits for d, it returns the error of ctx when ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
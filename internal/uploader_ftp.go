@@ -0,0 +1,368 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/jlaffaye/ftp"
+)
+
+const (
+	defaultFTPTimeout    = 30 * time.Second
+	defaultFTPRetryDelay = 5 * time.Second
+	// ftpResumeCheckSize is how much of the end of a partial file is compared with the
+	// artifact before the upload is resumed.
+	ftpResumeCheckSize = 64 << 10
+)
+
+type ftpUploader struct {
+	artifactsConfig config.ArtifactsConfig
+	conf            config.FTPConfig
+	address         string
+	tlsConfig       *tls.Config
+	limiter         *BandwidthLimiter
+	retryDelay      time.Duration
+}
+
+func NewFTPUploader(
+	conf config.FTPConfig,
+	artifactsConf config.ArtifactsConfig,
+	limiter *BandwidthLimiter,
+) (*ftpUploader, error) {
+	u := &ftpUploader{
+		artifactsConfig: artifactsConf,
+		conf:            conf,
+		address:         conf.Address,
+		limiter:         limiter,
+		retryDelay:      conf.RetryDelay,
+	}
+
+	if u.retryDelay == 0 {
+		u.retryDelay = defaultFTPRetryDelay
+	}
+
+	port := "21"
+	if conf.TLS != nil && conf.TLS.Implicit {
+		port = "990"
+	}
+
+	host, _, err := net.SplitHostPort(conf.Address)
+	if err != nil {
+		host = conf.Address
+		u.address = net.JoinHostPort(conf.Address, port)
+	}
+
+	if conf.TLS != nil {
+		u.tlsConfig, err = newTLSConfig(conf.TLS.HTTPSTLS)
+		if err != nil {
+			return nil, err
+		}
+
+		if u.tlsConfig.ServerName == "" {
+			u.tlsConfig.ServerName = host
+		}
+
+		// Most FTPS servers require data connections to resume the session of the control connection.
+		u.tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	return u, nil
+}
+
+func (u *ftpUploader) Upload(ctx context.Context, round Round) UploadResults {
+	log := slog.With("op", "ftpUploader.Upload")
+
+	results := make(UploadResults, len(round))
+
+	for typ, artifact := range round {
+		start := time.Now()
+		result := u.uploadFile(ctx, typ, artifact.Path)
+		result.Duration = time.Since(start)
+		results[typ] = result
+
+		if result.Err == nil {
+			log.Debug("uploaded file via FTP", "path", artifact.Path, "skipped", result.Skipped)
+		}
+	}
+
+	return results
+}
+
+// uploadFile uploads the file under its partial name and renames it once complete. An
+// interrupted upload is resumed on a new connection from the size of the partial file.
+func (u *ftpUploader) uploadFile(ctx context.Context, typ config.ArtifactType, filename string) UploadResult {
+	result := UploadResult{
+		RemotePath: path.Join(u.conf.BasePath, u.artifactsConfig[typ].UploadPath, u.artifactsConfig.RemoteName(typ, filename)),
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := u.withConn(ctx, func(conn *ftp.ServerConn) error {
+			return u.attempt(ctx, conn, typ, filename, &result)
+		})
+		if err == nil || attempt >= u.conf.Retries {
+			result.Err = err
+			return result
+		}
+
+		slog.Warn("FTP upload failed, resuming", "path", filename, "attempt", attempt+1, "err", err, "op", "ftpUploader.uploadFile")
+
+		if err := sleepContext(ctx, u.retryDelay); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+}
+
+func (u *ftpUploader) attempt(ctx context.Context, conn *ftp.ServerConn, typ config.ArtifactType, filename string, result *UploadResult) error {
+	if u.conf.Overwrite != config.OverwriteAlways {
+		skip, err := u.skip(conn, typ, filename, result.RemotePath)
+		if err != nil {
+			return err
+		}
+		if skip {
+			result.Skipped = true
+			return nil
+		}
+	}
+
+	ftpMakeDirs(conn, path.Dir(result.RemotePath))
+
+	partial := path.Join(path.Dir(result.RemotePath), partialName(path.Base(result.RemotePath)))
+
+	d, err := u.store(ctx, conn, typ, filename, partial)
+	if err != nil {
+		return err
+	}
+
+	result.Bytes = d.n
+
+	return ftpRename(conn, partial, result.RemotePath)
+}
+
+// store uploads the file to partial, continuing after the data already there unless the
+// file is encrypted, as encryption differs on every read, or the data there is not the start
+// of the artifact. It returns the digest of the whole file.
+func (u *ftpUploader) store(ctx context.Context, conn *ftp.ServerConn, typ config.ArtifactType, filename, partial string) (*digest, error) {
+	loc := u.artifactsConfig[typ]
+
+	var offset int64
+	if loc.Encrypt == nil {
+		var err error
+		offset, err = conn.FileSize(partial)
+		if err != nil {
+			// Missing, or the server does not support SIZE.
+			offset = 0
+		}
+	}
+
+	file, err := openArtifact(filename, loc)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	d := newDigest()
+
+	if offset > 0 {
+		// Compression is deterministic, the bytes of an earlier attempt are the start of the stream.
+		resumable, err := ftpPartialMatches(conn, partial, file, d, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if resumable {
+			slog.Info("resuming FTP upload", "path", filename, "offset", offset, "op", "ftpUploader.store")
+		} else {
+			slog.Warn("partial file does not match the artifact, starting over", "path", filename, "partial", partial, "op", "ftpUploader.store")
+
+			if err := conn.Delete(partial); err != nil {
+				slog.Debug("cannot delete partial file", "path", partial, "err", err, "op", "ftpUploader.store")
+			}
+
+			file.Close()
+			file, err = openArtifact(filename, loc)
+			if err != nil {
+				return nil, err
+			}
+			d = newDigest()
+			offset = 0
+		}
+	}
+
+	if err := conn.StorFrom(partial, io.TeeReader(u.limiter.Reader(ctx, file), d), uint64(offset)); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (u *ftpUploader) skip(conn *ftp.ServerConn, typ config.ArtifactType, filename, remotePath string) (bool, error) {
+	remote := remoteFile{Exists: true}
+
+	size, err := conn.FileSize(remotePath)
+	switch ftpStatus(err) {
+	case 0:
+		if err != nil {
+			return false, err
+		}
+		remote.Size = size
+	case ftp.StatusFileUnavailable:
+		remote.Exists = false
+	case ftp.StatusBadCommand, ftp.StatusNotImplemented:
+		// SIZE is not supported, the size is unknown and the file is looked up in its directory.
+		remote.Size = -1
+		remote.Exists, err = ftpExists(conn, remotePath)
+		if err != nil {
+			return false, err
+		}
+	default:
+		return false, err
+	}
+
+	return skipUpload(u.conf.Overwrite, remote, filename, u.artifactsConfig[typ])
+}
+
+// Check tells whether the FTP server accepts the credentials.
+func (u *ftpUploader) Check() error {
+	return u.withConn(context.Background(), func(conn *ftp.ServerConn) error {
+		return conn.NoOp()
+	})
+}
+
+// withConn runs fn on a new logged in connection, ctx bounds the dial.
+func (u *ftpUploader) withConn(ctx context.Context, fn func(*ftp.ServerConn) error) error {
+	timeout := u.conf.Timeout
+	if timeout == 0 {
+		timeout = defaultFTPTimeout
+	}
+
+	opts := []ftp.DialOption{
+		ftp.DialWithContext(ctx),
+		ftp.DialWithTimeout(timeout),
+		ftp.DialWithDisabledEPSV(u.conf.DisableEPSV),
+	}
+
+	if u.tlsConfig != nil {
+		if u.conf.TLS.Implicit {
+			opts = append(opts, ftp.DialWithTLS(u.tlsConfig))
+		} else {
+			opts = append(opts, ftp.DialWithExplicitTLS(u.tlsConfig))
+		}
+	}
+
+	conn, err := ftp.Dial(u.address, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+
+	if err := conn.Login(u.conf.Username, u.conf.Password); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ftpMakeDirs creates dir and its parents. MKD fails for existing directories and servers
+// differ in how they report it, so errors are ignored and a missing directory fails the upload.
+func ftpMakeDirs(conn *ftp.ServerConn, dir string) {
+	current := ""
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+
+	for _, segment := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if segment == "" || segment == "." {
+			continue
+		}
+
+		current = path.Join(current, segment)
+		_ = conn.MakeDir(current)
+	}
+}
+
+// ftpStatus returns the status code of an FTP error, 0 for other errors.
+func ftpStatus(err error) int {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code
+	}
+
+	return 0
+}
+
+// ftpExists looks for the file in the listing of its directory.
+func ftpExists(conn *ftp.ServerConn, file string) (bool, error) {
+	names, err := conn.NameList(path.Dir(file))
+	if ftpStatus(err) == ftp.StatusFileUnavailable {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if path.Base(name) == path.Base(file) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ftpPartialMatches reads the first offset bytes of local into d and tells whether the partial
+// file holds them, by comparing the end of both. A partial file that cannot be compared does not
+// match. Errors are only returned for local reads.
+func ftpPartialMatches(conn *ftp.ServerConn, partial string, local io.Reader, d io.Writer, offset int64) (bool, error) {
+	tail := min(offset, ftpResumeCheckSize)
+
+	want := make([]byte, tail)
+	_, err := io.CopyN(d, local, offset-tail)
+	if err == nil {
+		_, err = io.ReadFull(local, want)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// The partial file is longer than the artifact.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	d.Write(want)
+
+	resp, err := conn.RetrFrom(partial, uint64(offset-tail))
+	if err != nil {
+		return false, nil
+	}
+	defer resp.Close()
+
+	got := make([]byte, tail)
+	if _, err := io.ReadFull(resp, got); err != nil {
+		return false, nil
+	}
+
+	return bytes.Equal(want, got), nil
+}
+
+// ftpRename moves from to to, replacing to on servers that refuse to rename over a file.
+func ftpRename(conn *ftp.ServerConn, from, to string) error {
+	if err := conn.Rename(from, to); err == nil {
+		return nil
+	}
+
+	if err := conn.Delete(to); err != nil {
+		slog.Debug("cannot delete rename destination", "path", to, "err", err, "op", "ftpRename")
+	}
+
+	return conn.Rename(from, to)
+}
@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// ftpServer is a minimal passive mode FTP server keeping files in memory. The STOR
+// requests listed in failStors are aborted after failAfter bytes. With noSize set,
+// SIZE is not implemented.
+type ftpServer struct {
+	addr string
+
+	mu        sync.Mutex
+	noSize    bool
+	files     map[string][]byte
+	dirs      map[string]bool
+	stors     int
+	rests     []int64
+	failStors map[int]bool
+	failAfter int64
+}
+
+func newFTPServer(t *testing.T) *ftpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := &ftpServer{
+		addr:  ln.Addr().String(),
+		files: make(map[string][]byte),
+		dirs:  make(map[string]bool),
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *ftpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 ready")
+
+	var (
+		data       net.Listener
+		offset     int64
+		renameFrom string
+	)
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		s.mu.Lock()
+		switch cmd {
+		case "USER":
+			tp.PrintfLine("331 password required")
+		case "PASS":
+			if arg != "secret" {
+				tp.PrintfLine("530 login incorrect")
+			} else {
+				tp.PrintfLine("230 logged in")
+			}
+		case "TYPE":
+			tp.PrintfLine("200 ok")
+		case "EPSV":
+			data, err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				tp.PrintfLine("425 %s", err)
+				break
+			}
+			tp.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "REST":
+			offset, _ = strconv.ParseInt(arg, 10, 64)
+			s.rests = append(s.rests, offset)
+			tp.PrintfLine("350 restarting at %d", offset)
+		case "STOR":
+			s.stors++
+			fail := s.failStors[s.stors]
+			s.mu.Unlock()
+			s.stor(tp, data, arg, offset, fail)
+			s.mu.Lock()
+			offset = 0
+		case "RETR":
+			content, ok := s.files[arg]
+			s.mu.Unlock()
+			s.send(tp, data, ok, func(w io.Writer) { w.Write(content[offset:]) })
+			s.mu.Lock()
+			offset = 0
+		case "NLST":
+			var names []string
+			for name := range s.files {
+				if path.Dir(name) == arg {
+					names = append(names, name)
+				}
+			}
+			s.mu.Unlock()
+			s.send(tp, data, s.dirs[arg], func(w io.Writer) {
+				for _, name := range names {
+					io.WriteString(w, name+"\r\n")
+				}
+			})
+			s.mu.Lock()
+		case "DELE":
+			if _, ok := s.files[arg]; ok {
+				delete(s.files, arg)
+				tp.PrintfLine("250 deleted")
+			} else {
+				tp.PrintfLine("550 no such file")
+			}
+		case "SIZE":
+			if s.noSize {
+				tp.PrintfLine("502 not implemented")
+			} else if content, ok := s.files[arg]; ok {
+				tp.PrintfLine("213 %d", len(content))
+			} else {
+				tp.PrintfLine("550 no such file")
+			}
+		case "MKD":
+			if s.dirs[arg] {
+				tp.PrintfLine("550 exists")
+			} else {
+				s.dirs[arg] = true
+				tp.PrintfLine("257 created")
+			}
+		case "RNFR":
+			renameFrom = arg
+			tp.PrintfLine("350 ready")
+		case "RNTO":
+			s.files[arg] = s.files[renameFrom]
+			delete(s.files, renameFrom)
+			tp.PrintfLine("250 renamed")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// send writes the response of RETR or NLST to the data connection, or fails with 550 when not found.
+func (s *ftpServer) send(tp *textproto.Conn, data net.Listener, found bool, write func(io.Writer)) {
+	dc, err := data.Accept()
+	data.Close()
+	if err != nil {
+		tp.PrintfLine("425 %s", err)
+		return
+	}
+
+	if !found {
+		dc.Close()
+		tp.PrintfLine("550 not found")
+		return
+	}
+
+	tp.PrintfLine("150 ok")
+	write(dc)
+	dc.Close()
+	tp.PrintfLine("226 done")
+}
+
+func (s *ftpServer) stor(tp *textproto.Conn, data net.Listener, name string, offset int64, fail bool) {
+	dc, err := data.Accept()
+	data.Close()
+	if err != nil {
+		tp.PrintfLine("425 %s", err)
+		return
+	}
+	defer dc.Close()
+
+	tp.PrintfLine("150 ok")
+
+	var r io.Reader = dc
+	if fail {
+		r = io.LimitReader(dc, s.failAfter)
+	}
+
+	body, _ := io.ReadAll(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirs[path.Dir(name)] {
+		tp.PrintfLine("553 no such directory")
+		return
+	}
+
+	s.files[name] = append(s.files[name][:offset:offset], body...)
+
+	if fail {
+		tp.PrintfLine("426 transfer aborted")
+		return
+	}
+
+	tp.PrintfLine("226 done")
+}
+
+func TestFTPUploader(t *testing.T) {
+	content := bytes.Repeat([]byte("a battle recorder "), 1024)
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	srv := newFTPServer(t)
+	srv.failStors = map[int]bool{1: true}
+	srv.failAfter = 16
+
+	uploader, err := NewFTPUploader(config.FTPConfig{
+		Address:    srv.addr,
+		Username:   "pr",
+		Password:   "secret",
+		BasePath:   "/srv/pr",
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "demos/bf2demos", Compress: config.CompressionGzip},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	results := uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+
+	remotePath := "/srv/pr/demos/bf2demos/file1.bf2demo.gz"
+	require.Equal(t, remotePath, results[config.ArtifactTypeBF2Demo].RemotePath)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	require.Equal(t, []int64{16}, srv.rests)
+	require.Len(t, srv.files, 1)
+
+	size, sum, err := hashArtifact(path, config.Location{Compress: config.CompressionGzip})
+	require.NoError(t, err)
+	require.Equal(t, size, results[config.ArtifactTypeBF2Demo].Bytes)
+
+	d := newDigest()
+	d.Write(srv.files[remotePath])
+	require.Equal(t, sum, d.Hex())
+}
+
+func TestFTPUploaderErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	tests := []struct {
+		name      string
+		password  string
+		overwrite config.Overwrite
+		noSize    bool
+		remote    string
+		skipped   bool
+		err       string
+	}{
+		{name: "wrong password", password: "wrong", err: "login incorrect"},
+		{name: "never, existing", password: "secret", overwrite: config.OverwriteNever, remote: "curated", skipped: true},
+		{name: "if-different, same size", password: "secret", overwrite: config.OverwriteIfDifferent, remote: "tset", skipped: true},
+		{name: "if-different, different", password: "secret", overwrite: config.OverwriteIfDifferent, remote: "curated"},
+		{name: "never, no SIZE, existing", password: "secret", overwrite: config.OverwriteNever, noSize: true, remote: "curated", skipped: true},
+		{name: "never, no SIZE, missing", password: "secret", overwrite: config.OverwriteNever, noSize: true},
+		{name: "if-different, no SIZE", password: "secret", overwrite: config.OverwriteIfDifferent, noSize: true, remote: "tset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFTPServer(t)
+			srv.noSize = tt.noSize
+			srv.dirs["bf2demos"] = true
+			if tt.remote != "" {
+				srv.files["bf2demos/file1.bf2demo"] = []byte(tt.remote)
+			}
+
+			uploader, err := NewFTPUploader(config.FTPConfig{
+				Address:   srv.addr,
+				Username:  "pr",
+				Password:  tt.password,
+				Overwrite: tt.overwrite,
+			}, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			results := uploader.Upload(context.Background(), round)
+			if tt.err != "" {
+				require.ErrorContains(t, results.Err(round), tt.err)
+				return
+			}
+			require.NoError(t, results.Err(round))
+			require.Equal(t, tt.skipped, results[config.ArtifactTypeBF2Demo].Skipped)
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+
+			expected := "test"
+			if tt.skipped {
+				expected = tt.remote
+			}
+			require.Equal(t, expected, string(srv.files["bf2demos/file1.bf2demo"]))
+		})
+	}
+}
+
+func TestFTPUploaderStalePartial(t *testing.T) {
+	content := bytes.Repeat([]byte("a battle recorder "), 1024)
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	srv := newFTPServer(t)
+	srv.dirs["bf2demos"] = true
+	// Left by an upload of another artifact with the same name.
+	srv.files["bf2demos/.file1.bf2demo.partial"] = []byte("another battle recorder")
+
+	uploader, err := NewFTPUploader(config.FTPConfig{
+		Address:  srv.addr,
+		Username: "pr",
+		Password: "secret",
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+	require.NoError(t, uploader.Upload(context.Background(), round).Err(round))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	require.Empty(t, srv.rests)
+	require.Equal(t, map[string][]byte{"bf2demos/file1.bf2demo": content}, srv.files)
+}
@@ -1,51 +1,136 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/emilekm/artifacts-mover/internal/config"
 )
 
+const defaultChecksumHeader = "X-Checksum-SHA256"
+
 type httpsUploader struct {
 	artifactsConfig config.ArtifactsConfig
 	conf            config.HTTPSConfig
+	client          *http.Client
+	limiter         *BandwidthLimiter
 }
 
 func NewHTTPSUploader(
 	conf config.HTTPSConfig,
 	artifactsConf config.ArtifactsConfig,
-) *httpsUploader {
+	limiter *BandwidthLimiter,
+) (*httpsUploader, error) {
+	client, err := newHTTPClient(conf.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
 	return &httpsUploader{
 		artifactsConfig: artifactsConf,
 		conf:            conf,
-	}
+		client:          client,
+		limiter:         limiter,
+	}, nil
 }
 
-func (u *httpsUploader) Upload(round Round) error {
+func (u *httpsUploader) Upload(ctx context.Context, round Round) UploadResults {
 	log := slog.With("op", "httpsUploader.Upload")
 
+	results := make(UploadResults, len(round))
+
 	for typ, artifact := range round {
-		err := u.uploadFile(typ, artifact.Path)
+		start := time.Now()
+		result := u.uploadFile(ctx, typ, artifact.Path)
+		result.Duration = time.Since(start)
+		results[typ] = result
+
+		if result.Err == nil {
+			log.Debug("uploaded file via HTTPS", "path", artifact.Path, "url", result.URL, "skipped", result.Skipped)
+		}
+	}
+
+	return results
+}
+
+// uploadFile uploads the file, the result has its download URL when the server reports it.
+func (u *httpsUploader) uploadFile(ctx context.Context, typ config.ArtifactType, filename string) UploadResult {
+	remoteName := u.artifactsConfig.RemoteName(typ, filename)
+
+	result := UploadResult{RemotePath: path.Join(u.artifactsConfig[typ].UploadPath, remoteName)}
+
+	if u.conf.Overwrite != config.OverwriteAlways {
+		skip, err := u.skip(ctx, typ, filename)
 		if err != nil {
-			return err
+			result.Err = err
+			return result
+		}
+		if skip {
+			result.Skipped = true
+			return result
 		}
-		log.Debug("uploaded file via HTTPS", "path", artifact.Path)
 	}
 
-	return nil
+	name := remoteName
+	if u.conf.TwoPhase {
+		name = partialName(name)
+	}
+
+	var (
+		resp *uploadResponse
+		d    *digest
+		err  error
+	)
+	if u.conf.Resumable != nil {
+		resp, d, err = u.uploadResumable(ctx, typ, filename, name)
+	} else {
+		resp, d, err = u.postFile(ctx, typ, filename, name)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Bytes = d.n
+
+	if u.conf.Verify {
+		if err := u.verify(resp.Header, filename, d.Hex()); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if u.conf.TwoPhase {
+		resp, err = u.commit(ctx, typ, filename)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	result.URL, err = u.link(resp)
+	if err != nil {
+		// The file is uploaded, notifications fall back to the configured URLs.
+		slog.Warn("cannot read download URL from upload response", "err", err, "path", filename, "op", "httpsUploader.uploadFile")
+	}
+
+	return result
 }
 
-func (u *httpsUploader) uploadFile(typ config.ArtifactType, filename string) error {
-	file, err := os.Open(filename)
+// postFile uploads the file in a single multipart request, it returns the response
+// and the digest of the data sent.
+func (u *httpsUploader) postFile(ctx context.Context, typ config.ArtifactType, filename, name string) (*uploadResponse, *digest, error) {
+	file, err := openArtifact(filename, u.artifactsConfig[typ])
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer file.Close()
 
@@ -53,18 +138,20 @@ func (u *httpsUploader) uploadFile(typ config.ArtifactType, filename string) err
 
 	mw := multipart.NewWriter(pw)
 
+	h := newDigest()
+
 	errCh := make(chan error, 1)
 	go func() {
 		defer pw.Close()
 		defer mw.Close()
 
-		part, err := mw.CreateFormFile("artifact", filepath.Base(filename))
+		part, err := mw.CreateFormFile("artifact", name)
 		if err != nil {
 			errCh <- err
 			return
 		}
 
-		if _, err := io.Copy(part, file); err != nil {
+		if _, err := io.Copy(io.MultiWriter(part, h), u.limiter.Reader(ctx, file)); err != nil {
 			errCh <- err
 			return
 		}
@@ -74,38 +161,149 @@ func (u *httpsUploader) uploadFile(typ config.ArtifactType, filename string) err
 
 	uri, err := url.JoinPath(u.conf.URL, u.artifactsConfig[typ].UploadPath)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequest("POST", uri, pr)
+	req, err := http.NewRequestWithContext(ctx, "POST", uri, pr)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	req.Header.Set("Content-Type", mw.FormDataContentType())
 
-	if len(u.conf.Auth.Headers) > 0 {
-		for k, v := range u.conf.Auth.Headers {
-			req.Header.Set(k, v)
-		}
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if copyErr := <-errCh; copyErr != nil {
+		return nil, nil, copyErr
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("upload failed with status: %s", resp.Status)
+	}
+
+	uploadResp, err := readUploadResponse(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return uploadResp, h, nil
+}
+
+// commit moves the file uploaded under its partial name to the final one, with a WebDAV style MOVE request.
+func (u *httpsUploader) commit(ctx context.Context, typ config.ArtifactType, filename string) (*uploadResponse, error) {
+	name := u.artifactsConfig.RemoteName(typ, filename)
+
+	src, err := url.JoinPath(u.conf.URL, u.artifactsConfig[typ].UploadPath, partialName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := url.JoinPath(u.conf.URL, u.artifactsConfig[typ].UploadPath, name)
+	if err != nil {
+		return nil, err
 	}
 
-	if u.conf.Auth.Basic != nil {
-		req.SetBasicAuth(u.conf.Auth.Basic.Username, u.conf.Auth.Basic.Password)
+	req, err := http.NewRequestWithContext(ctx, "MOVE", src, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	req.Header.Set("Destination", dst)
+	req.Header.Set("Overwrite", "T")
+
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if copyErr := <-errCh; copyErr != nil {
-		return copyErr
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("commit failed with status: %s", resp.Status)
+	}
+
+	return readUploadResponse(resp)
+}
+
+// Check tells whether the upload server is reachable.
+func (u *httpsUploader) Check() error {
+	return checkHTTP(u.client, u.conf.URL, u.conf.Auth)
+}
+
+func (u *httpsUploader) setAuth(req *http.Request) {
+	setAuth(req, u.conf.Auth)
+}
+
+func (u *httpsUploader) skip(ctx context.Context, typ config.ArtifactType, filename string) (bool, error) {
+	remote, err := u.stat(ctx, typ, filename)
+	if err != nil {
+		return false, err
+	}
+
+	return skipUpload(u.conf.Overwrite, remote, filename, u.artifactsConfig[typ])
+}
+
+// stat asks for the uploaded file with a HEAD request to the upload URL followed by its remote name.
+func (u *httpsUploader) stat(ctx context.Context, typ config.ArtifactType, filename string) (remoteFile, error) {
+	uri, err := url.JoinPath(u.conf.URL, u.artifactsConfig[typ].UploadPath, u.artifactsConfig.RemoteName(typ, filename))
+	if err != nil {
+		return remoteFile{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uri, nil)
+	if err != nil {
+		return remoteFile{}, err
+	}
+
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return remoteFile{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return remoteFile{}, nil
 	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("upload failed with status: %s", resp.Status)
+		return remoteFile{}, fmt.Errorf("stat failed with status: %s", resp.Status)
+	}
+
+	return remoteFile{
+		Exists: true,
+		Size:   resp.ContentLength,
+		SHA256: resp.Header.Get(u.checksumHeader()),
+	}, nil
+}
+
+func (u *httpsUploader) checksumHeader() string {
+	if u.conf.ChecksumHeader != "" {
+		return u.conf.ChecksumHeader
+	}
+
+	return defaultChecksumHeader
+}
+
+// verify compares the checksum the server reports for the stored file with the one of the uploaded data.
+func (u *httpsUploader) verify(respHeader http.Header, filename, sum string) error {
+	header := u.checksumHeader()
+
+	remoteSum := respHeader.Get(header)
+	if remoteSum == "" {
+		return fmt.Errorf("upload response has no %s header", header)
+	}
+
+	if !strings.EqualFold(remoteSum, sum) {
+		return checksumMismatch(filename, sum, remoteSum)
 	}
 
 	return nil
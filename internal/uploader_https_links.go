@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const maxUploadResponseSize = 1 << 20
+
+// uploadResponse is the server's answer to the request completing an upload.
+type uploadResponse struct {
+	Header http.Header
+	Body   []byte
+	// URL is the URL of the request, relative links are resolved against it.
+	URL *url.URL
+}
+
+func readUploadResponse(resp *http.Response) (*uploadResponse, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUploadResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &uploadResponse{
+		Header: resp.Header,
+		Body:   body,
+		URL:    resp.Request.URL,
+	}, nil
+}
+
+// link returns the download URL from the upload response, or an empty string
+// when links are not configured.
+func (u *httpsUploader) link(resp *uploadResponse) (string, error) {
+	if u.conf.Links == nil {
+		return "", nil
+	}
+
+	var link string
+
+	switch {
+	case u.conf.Links.JSONPath != "":
+		var body any
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			return "", fmt.Errorf("cannot decode upload response: %w", err)
+		}
+
+		value, err := lookupJSONPath(body, u.conf.Links.JSONPath)
+		if err != nil {
+			return "", err
+		}
+
+		link = value
+	case u.conf.Links.Location:
+		link = resp.Header.Get("Location")
+		if link == "" {
+			return "", errors.New("upload response has no Location header")
+		}
+	default:
+		return "", nil
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid link in upload response: %w", err)
+	}
+
+	if resp.URL != nil {
+		ref = resp.URL.ResolveReference(ref)
+	}
+
+	return ref.String(), nil
+}
+
+// lookupJSONPath returns the string at the dot separated path in the decoded JSON value,
+// array elements are addressed by their index.
+func lookupJSONPath(value any, path string) (string, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			value, ok = v[key]
+			if !ok {
+				return "", fmt.Errorf("upload response has no %s", path)
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("upload response has no %s", path)
+			}
+			value = v[i]
+		default:
+			return "", fmt.Errorf("upload response has no %s", path)
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("%s in upload response is not a URL", path)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,320 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSUploaderVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	tests := []struct {
+		name     string
+		checksum string
+		err      string
+	}{
+		{
+			name:     "matching",
+			checksum: "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+		},
+		{
+			name:     "mismatch",
+			checksum: "0000000000000000000000000000000000000000000000000000000000000000",
+			err:      "checksum mismatch",
+		},
+		{
+			name: "missing header",
+			err:  "no X-Checksum-SHA256 header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.checksum != "" {
+					w.Header().Set(defaultChecksumHeader, tt.checksum)
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			uploader, err := NewHTTPSUploader(config.HTTPSConfig{URL: srv.URL, Verify: true}, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			err = uploader.Upload(context.Background(), round).Err(round)
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHTTPSUploaderOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	tests := []struct {
+		name     string
+		policy   config.Overwrite
+		remote   string
+		uploaded bool
+	}{
+		{name: "always", policy: config.OverwriteAlways, remote: "test", uploaded: true},
+		{name: "never, missing", policy: config.OverwriteNever, uploaded: true},
+		{name: "never, existing", policy: config.OverwriteNever, remote: "curated"},
+		{name: "if-different, same", policy: config.OverwriteIfDifferent, remote: "test"},
+		{name: "if-different, different", policy: config.OverwriteIfDifferent, remote: "tset", uploaded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploaded := false
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					require.Equal(t, "/bf2demos/file1.bf2demo", r.URL.Path)
+					if tt.remote == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					sum := sha256.Sum256([]byte(tt.remote))
+					w.Header().Set(defaultChecksumHeader, hex.EncodeToString(sum[:]))
+					w.Header().Set("Content-Length", strconv.Itoa(len(tt.remote)))
+				case http.MethodPost:
+					uploaded = true
+				}
+			}))
+			defer srv.Close()
+
+			uploader, err := NewHTTPSUploader(config.HTTPSConfig{URL: srv.URL, Overwrite: tt.policy}, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			require.NoError(t, uploader.Upload(context.Background(), round).Err(round))
+			require.Equal(t, tt.uploaded, uploaded)
+		})
+	}
+}
+
+func TestHTTPSUploaderTwoPhase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			_, header, err := r.FormFile("artifact")
+			require.NoError(t, err)
+			requests = append(requests, "POST "+r.URL.Path+" "+header.Filename)
+		case "MOVE":
+			requests = append(requests, "MOVE "+r.URL.Path+" "+r.Header.Get("Destination"))
+		}
+	}))
+	defer srv.Close()
+
+	uploader, err := NewHTTPSUploader(config.HTTPSConfig{URL: srv.URL, TwoPhase: true}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+	require.NoError(t, uploader.Upload(context.Background(), round).Err(round))
+	require.Equal(t, []string{
+		"POST /bf2demos .file1.bf2demo.partial",
+		"MOVE /bf2demos/.file1.bf2demo.partial " + srv.URL + "/bf2demos/file1.bf2demo",
+	}, requests)
+}
+
+// tusServer is a minimal tus server failing the PATCH requests listed in failPatches.
+// With gone set, it answers HEAD requests as if the upload expired.
+type tusServer struct {
+	mu          sync.Mutex
+	gone        bool
+	creates     int
+	patches     int
+	failPatches map[int]bool
+	length      int64
+	filename    string
+	data        []byte
+}
+
+func (s *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPost:
+		s.creates++
+		s.length, _ = strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		name, _ := strings.CutPrefix(r.Header.Get("Upload-Metadata"), "filename ")
+		filename, _ := base64.StdEncoding.DecodeString(name)
+		s.filename = string(filename)
+		s.data = nil
+		w.Header().Set("Location", "/files/1")
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodHead:
+		if s.gone {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+	case http.MethodPatch:
+		s.patches++
+		if s.failPatches[s.patches] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Upload-Offset") != strconv.Itoa(len(s.data)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		s.data = append(s.data, chunk...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestHTTPSUploaderResumable(t *testing.T) {
+	content := []byte("a battle recorder that takes a while to upload")
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	t.Run("retries", func(t *testing.T) {
+		tus := &tusServer{failPatches: map[int]bool{3: true, 4: true}}
+		srv := httptest.NewServer(tus)
+		defer srv.Close()
+
+		uploader, err := NewHTTPSUploader(config.HTTPSConfig{
+			URL:       srv.URL,
+			Resumable: &config.Resumable{ChunkSize: 8, Retries: 2, RetryDelay: time.Millisecond},
+		}, config.ArtifactsConfig{config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"}}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, uploader.Upload(context.Background(), round).Err(round))
+		require.Equal(t, 1, tus.creates)
+		require.Equal(t, "file1.bf2demo", tus.filename)
+		require.Equal(t, int64(len(content)), tus.length)
+		require.Equal(t, content, tus.data)
+	})
+
+	t.Run("upload gone", func(t *testing.T) {
+		tus := &tusServer{failPatches: map[int]bool{3: true}}
+		srv := httptest.NewServer(tus)
+		defer srv.Close()
+
+		uploader, err := NewHTTPSUploader(config.HTTPSConfig{
+			URL:       srv.URL,
+			Resumable: &config.Resumable{ChunkSize: 8, Retries: 1, RetryDelay: time.Millisecond},
+		}, config.ArtifactsConfig{config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"}}, nil)
+		require.NoError(t, err)
+
+		tus.gone = true
+		require.NoError(t, uploader.Upload(context.Background(), round).Err(round))
+		require.Equal(t, 2, tus.creates)
+		require.Equal(t, content, tus.data)
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		tus := &tusServer{failPatches: map[int]bool{1: true}}
+		srv := httptest.NewServer(tus)
+		defer srv.Close()
+
+		uploader, err := NewHTTPSUploader(config.HTTPSConfig{
+			URL:       srv.URL,
+			Resumable: &config.Resumable{ChunkSize: 8, Retries: 1, RetryDelay: time.Hour},
+		}, config.ArtifactsConfig{config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"}}, nil)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, uploader.Upload(ctx, round).Err(round), context.DeadlineExceeded)
+	})
+}
+
+func TestHTTPSUploaderLinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	tests := []struct {
+		name  string
+		links *config.HTTPSLinks
+		url   string
+	}{
+		{name: "disabled"},
+		{
+			name:  "json path",
+			links: &config.HTTPSLinks{JSONPath: "files.0.url"},
+			url:   "https://cdn.example.com/a1b2c3.bf2demo",
+		},
+		{
+			name:  "location",
+			links: &config.HTTPSLinks{Location: true},
+			url:   "/download/a1b2c3.bf2demo",
+		},
+		{
+			name:  "missing json path",
+			links: &config.HTTPSLinks{JSONPath: "files.1.url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Location", "/download/a1b2c3.bf2demo")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"files": [{"url": "https://cdn.example.com/a1b2c3.bf2demo"}]}`))
+			}))
+			defer srv.Close()
+
+			uploader, err := NewHTTPSUploader(config.HTTPSConfig{URL: srv.URL, Links: tt.links}, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+			results := uploader.Upload(context.Background(), round)
+			require.NoError(t, results.Err(round))
+			require.Equal(t, "bf2demos/file1.bf2demo", results[config.ArtifactTypeBF2Demo].RemotePath)
+			require.Equal(t, int64(4), results[config.ArtifactTypeBF2Demo].Bytes)
+
+			expected := tt.url
+			if strings.HasPrefix(expected, "/") {
+				expected = srv.URL + expected
+			}
+			require.Equal(t, expected, results[config.ArtifactTypeBF2Demo].URL)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+const (
+	tusVersion = "1.0.0"
+
+	defaultChunkSize        = 8 << 20
+	defaultTusRetryDelay    = 5 * time.Second
+	maxTusRetryDelay        = time.Minute
+	tusContentType          = "application/offset+octet-stream"
+	tusUploadOffsetHeader   = "Upload-Offset"
+	tusResumableHeader      = "Tus-Resumable"
+	tusUploadLengthHeader   = "Upload-Length"
+	tusUploadMetadataHeader = "Upload-Metadata"
+)
+
+// errTusUploadGone is returned when the server no longer knows an upload.
+var errTusUploadGone = errors.New("upload no longer exists")
+
+// uploadResumable uploads the file with the tus protocol: the upload is created with a POST
+// to the upload URL and sent in chunks with PATCH requests. An interrupted upload is resumed
+// from the offset reported by a HEAD request, or started over when the server no longer knows it.
+// Encrypted files differ on every read, so their interrupted uploads start over instead.
+func (u *httpsUploader) uploadResumable(ctx context.Context, typ config.ArtifactType, filename, name string) (*uploadResponse, *digest, error) {
+	log := slog.With("op", "httpsUploader.uploadResumable", "path", filename)
+
+	loc := u.artifactsConfig[typ]
+
+	size, err := uploadSize(filename, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restart := loc.Encrypt != nil
+
+	location, err := u.tusCreate(ctx, typ, name, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Uploads run while the handler is locked, so the wait is capped.
+	retryDelay := u.conf.Resumable.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultTusRetryDelay
+	}
+	retryDelay = min(retryDelay, maxTusRetryDelay)
+
+	var offset int64
+	for attempt := 0; ; attempt++ {
+		resp, d, err := u.tusSend(ctx, location, filename, loc, offset, size)
+		if err == nil {
+			return resp, d, nil
+		}
+
+		if attempt >= u.conf.Resumable.Retries {
+			return nil, nil, err
+		}
+
+		log.Warn("upload interrupted, resuming", "err", err, "attempt", attempt+1)
+
+		if err := sleepContext(ctx, retryDelay); err != nil {
+			return nil, nil, err
+		}
+
+		if !restart {
+			offset, err = u.tusOffset(ctx, location)
+			if errors.Is(err, errTusUploadGone) {
+				log.Warn("upload expired on the server, starting over", "err", err)
+				restart = true
+			}
+		}
+		if restart {
+			offset = 0
+			location, err = u.tusCreate(ctx, typ, name, size)
+			restart = loc.Encrypt != nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// tusCreate creates the upload and returns its URL.
+func (u *httpsUploader) tusCreate(ctx context.Context, typ config.ArtifactType, name string, size int64) (string, error) {
+	uri, err := url.JoinPath(u.conf.URL, u.artifactsConfig[typ].UploadPath)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set(tusResumableHeader, tusVersion)
+	req.Header.Set(tusUploadLengthHeader, strconv.FormatInt(size, 10))
+	req.Header.Set(tusUploadMetadataHeader, "filename "+base64.StdEncoding.EncodeToString([]byte(name)))
+
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("creating upload failed with status: %s", resp.Status)
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("creating upload: %w", err)
+	}
+
+	return location.String(), nil
+}
+
+// tusOffset asks the server how much of the upload it has received.
+// A 404 or 410 is reported as errTusUploadGone.
+func (u *httpsUploader) tusOffset(ctx context.Context, location string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, location, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set(tusResumableHeader, tusVersion)
+
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return 0, fmt.Errorf("querying upload offset: %w: %s", errTusUploadGone, resp.Status)
+	}
+
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("querying upload offset failed with status: %s", resp.Status)
+	}
+
+	return strconv.ParseInt(resp.Header.Get(tusUploadOffsetHeader), 10, 64)
+}
+
+// tusSend sends the file from offset in chunks and returns the response to the last one.
+// The data before offset is read again to compute the digest of the whole upload.
+func (u *httpsUploader) tusSend(ctx context.Context, location, filename string, loc config.Location, offset, size int64) (*uploadResponse, *digest, error) {
+	file, err := openArtifact(filename, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	h := newDigest()
+
+	if _, err := io.CopyN(h, file, offset); err != nil {
+		return nil, nil, err
+	}
+
+	chunkSize := u.conf.Resumable.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	resp := &uploadResponse{}
+
+	limited := u.limiter.Reader(ctx, file)
+
+	chunk := make([]byte, chunkSize)
+	for offset < size {
+		n, err := io.ReadFull(limited, chunk[:min(chunkSize, size-offset)])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, offset, err = u.tusPatch(ctx, location, offset, chunk[:n], h)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return resp, h, nil
+}
+
+// tusPatch sends one chunk at offset and returns the offset the server is at afterwards.
+// The chunk is added to h once the server accepted it.
+func (u *httpsUploader) tusPatch(ctx context.Context, location string, offset int64, chunk []byte, h hash.Hash) (*uploadResponse, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, location, bytes.NewReader(chunk))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set(tusResumableHeader, tusVersion)
+	req.Header.Set(tusUploadOffsetHeader, strconv.FormatInt(offset, 10))
+	req.Header.Set("Content-Type", tusContentType)
+
+	u.setAuth(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, 0, fmt.Errorf("upload chunk failed with status: %s", resp.Status)
+	}
+
+	newOffset, err := strconv.ParseInt(resp.Header.Get(tusUploadOffsetHeader), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if newOffset != offset+int64(len(chunk)) {
+		return nil, 0, errors.New("server accepted only part of the chunk")
+	}
+
+	h.Write(chunk)
+
+	uploadResp, err := readUploadResponse(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return uploadResp, newOffset, nil
+}
+
+// uploadSize returns the number of bytes uploaded for the file to loc.
+func uploadSize(path string, loc config.Location) (int64, error) {
+	if loc.Raw() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+
+		return fi.Size(), nil
+	}
+
+	size, _, err := hashArtifact(path, loc)
+	return size, err
+}
@@ -0,0 +1,238 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+type localUploader struct {
+	artifactsConfig config.ArtifactsConfig
+	conf            config.LocalConfig
+	limiter         *BandwidthLimiter
+	// uid and gid are -1 when the ownership is kept.
+	uid, gid int
+}
+
+func NewLocalUploader(
+	conf config.LocalConfig,
+	artifactsConfig config.ArtifactsConfig,
+	limiter *BandwidthLimiter,
+) (*localUploader, error) {
+	u := &localUploader{
+		artifactsConfig: artifactsConfig,
+		conf:            conf,
+		limiter:         limiter,
+		uid:             -1,
+		gid:             -1,
+	}
+
+	if conf.Owner != "" {
+		uid, err := lookupID(conf.Owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		u.uid = uid
+	}
+
+	if conf.Group != "" {
+		gid, err := lookupID(conf.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		u.gid = gid
+	}
+
+	for typ := range artifactsConfig {
+		if err := os.MkdirAll(filepath.Join(conf.BasePath, artifactsConfig[typ].UploadPath), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// lookupID returns the numeric id, or resolves the name with lookup.
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+
+	id, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(id)
+}
+
+func (u *localUploader) Upload(ctx context.Context, round Round) UploadResults {
+	log := slog.With("op", "localUploader.Upload")
+
+	results := make(UploadResults, len(round))
+
+	for typ, artifact := range round {
+		start := time.Now()
+		result := u.uploadFile(ctx, typ, artifact.Path)
+		result.Duration = time.Since(start)
+		results[typ] = result
+
+		if result.Err == nil {
+			log.Debug("copied file to local path", "path", artifact.Path, "dst", result.RemotePath, "skipped", result.Skipped)
+		}
+	}
+
+	return results
+}
+
+func (u *localUploader) uploadFile(ctx context.Context, typ config.ArtifactType, path string) UploadResult {
+	dst := u.fullUploadPath(typ, path)
+
+	result := UploadResult{RemotePath: dst}
+
+	loc := u.artifactsConfig[typ]
+
+	if u.conf.Overwrite != config.OverwriteAlways {
+		skip, err := u.skip(dst, path, loc)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if skip {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	partial := filepath.Join(filepath.Dir(dst), partialName(filepath.Base(dst)))
+
+	bytes, err := u.place(ctx, path, partial, loc)
+	if err == nil {
+		err = u.setOwnership(partial)
+	}
+	if err == nil {
+		err = os.Rename(partial, dst)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(dst))
+	}
+	if err != nil {
+		os.Remove(partial)
+		result.Err = err
+		return result
+	}
+
+	result.Bytes = bytes
+
+	return result
+}
+
+// place puts the file at partial: hard-linked when it is uploaded as it is and on the same
+// device, copied otherwise. A link shares the mode and ownership of the original, so it is
+// only used when neither is changed. It returns the number of bytes written.
+func (u *localUploader) place(ctx context.Context, path, partial string, loc config.Location) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	mode := fi.Mode().Perm()
+	if u.conf.FileMode != 0 {
+		mode = os.FileMode(u.conf.FileMode)
+	}
+
+	// Leftover of an interrupted copy.
+	os.Remove(partial)
+
+	if loc.Raw() && !u.changesAttributes() {
+		if err := os.Link(path, partial); err == nil {
+			return fi.Size(), nil
+		}
+	}
+
+	r, err := openArtifact(path, loc)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	d := newDigest()
+	if err := writeFile(partial, io.TeeReader(u.limiter.Reader(ctx, r), d), mode); err != nil {
+		return 0, err
+	}
+
+	return d.n, nil
+}
+
+// changesAttributes tells whether published files get a mode or ownership of their own.
+func (u *localUploader) changesAttributes() bool {
+	return u.conf.FileMode != 0 || u.uid != -1 || u.gid != -1
+}
+
+func (u *localUploader) setOwnership(path string) error {
+	if u.uid == -1 && u.gid == -1 {
+		return nil
+	}
+
+	return os.Chown(path, u.uid, u.gid)
+}
+
+func (u *localUploader) skip(dst, path string, loc config.Location) (bool, error) {
+	fi, err := os.Stat(dst)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	remote := remoteFile{Exists: true, Size: fi.Size()}
+
+	if u.conf.Overwrite == config.OverwriteIfDifferent {
+		_, remote.SHA256, err = hashArtifact(dst, config.Location{})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return skipUpload(u.conf.Overwrite, remote, path, loc)
+}
+
+// Check tells whether the base path can be written to.
+func (u *localUploader) Check() error {
+	return checkWritable(u.conf.BasePath)
+}
+
+func (u *localUploader) fullUploadPath(typ config.ArtifactType, path string) string {
+	return filepath.Join(u.conf.BasePath, u.artifactsConfig[typ].UploadPath, u.artifactsConfig.RemoteName(typ, path))
+}
+
+// syncDir flushes a rename in dir to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalUploader(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "public")
+
+	bf2demo := filepath.Join(dir, "file1.bf2demo")
+	require.NoError(t, os.WriteFile(bf2demo, []byte("bf2demo"), 0600))
+
+	summary := filepath.Join(dir, "file1.json")
+	require.NoError(t, os.WriteFile(summary, []byte("summary"), 0600))
+
+	uploader, err := NewLocalUploader(config.LocalConfig{
+		BasePath: basePath,
+		FileMode: 0644,
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos", Compress: config.CompressionGzip},
+		config.ArtifactTypeSummary: {UploadPath: "summaries"},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{
+		config.ArtifactTypeBF2Demo: {Path: bf2demo, Type: config.ArtifactTypeBF2Demo},
+		config.ArtifactTypeSummary: {Path: summary, Type: config.ArtifactTypeSummary},
+	}
+
+	results := uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+
+	compressed := filepath.Join(basePath, "bf2demos", "file1.bf2demo.gz")
+	require.Equal(t, compressed, results[config.ArtifactTypeBF2Demo].RemotePath)
+
+	_, expected, err := hashArtifact(bf2demo, config.Location{Compress: config.CompressionGzip})
+	require.NoError(t, err)
+	_, actual, err := hashArtifact(compressed, config.Location{})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	copied := filepath.Join(basePath, "summaries", "file1.json")
+	content, err := os.ReadFile(copied)
+	require.NoError(t, err)
+	require.Equal(t, "summary", string(content))
+
+	for _, path := range []string{compressed, copied} {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+	}
+
+	// The file mode applies to a copy, the original is left alone.
+	source, err := os.Stat(summary)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), source.Mode().Perm())
+
+	published, err := os.Stat(copied)
+	require.NoError(t, err)
+	require.False(t, os.SameFile(source, published))
+
+	partials, err := filepath.Glob(filepath.Join(basePath, "*", ".*.partial"))
+	require.NoError(t, err)
+	require.Empty(t, partials)
+}
+
+func TestLocalUploaderHardlink(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "public")
+
+	summary := filepath.Join(dir, "file1.json")
+	require.NoError(t, os.WriteFile(summary, []byte("summary"), 0600))
+
+	uploader, err := NewLocalUploader(config.LocalConfig{BasePath: basePath}, config.ArtifactsConfig{
+		config.ArtifactTypeSummary: {UploadPath: "summaries"},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{config.ArtifactTypeSummary: {Path: summary, Type: config.ArtifactTypeSummary}}
+
+	results := uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+
+	source, err := os.Stat(summary)
+	require.NoError(t, err)
+	published, err := os.Stat(filepath.Join(basePath, "summaries", "file1.json"))
+	require.NoError(t, err)
+	require.True(t, os.SameFile(source, published))
+	require.Equal(t, os.FileMode(0600), source.Mode().Perm())
+}
+
+func TestLocalUploaderOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "public")
+
+	path := filepath.Join(dir, "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	tests := []struct {
+		name     string
+		policy   config.Overwrite
+		remote   string
+		uploaded bool
+	}{
+		{name: "always", policy: config.OverwriteAlways, remote: "tset", uploaded: true},
+		{name: "never, missing", policy: config.OverwriteNever, uploaded: true},
+		{name: "never, existing", policy: config.OverwriteNever, remote: "curated"},
+		{name: "if-different, same", policy: config.OverwriteIfDifferent, remote: "test"},
+		{name: "if-different, different", policy: config.OverwriteIfDifferent, remote: "tset", uploaded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader, err := NewLocalUploader(config.LocalConfig{BasePath: basePath, Overwrite: tt.policy}, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			dst := filepath.Join(basePath, "bf2demos", "file1.bf2demo")
+			os.Remove(dst)
+			if tt.remote != "" {
+				require.NoError(t, os.WriteFile(dst, []byte(tt.remote), 0644))
+			}
+
+			results := uploader.Upload(context.Background(), round)
+			require.NoError(t, results.Err(round))
+			require.Equal(t, !tt.uploaded, results[config.ArtifactTypeBF2Demo].Skipped)
+
+			content, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			if tt.uploaded {
+				require.Equal(t, "test", string(content))
+			} else {
+				require.Equal(t, tt.remote, string(content))
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/emilekm/artifacts-mover/internal/config"
@@ -20,11 +24,15 @@ type scpUploader struct {
 	address         string
 	privKeyFile     string
 	username        string
+	verify          bool
+	overwrite       config.Overwrite
+	limiter         *BandwidthLimiter
 }
 
 func NewSCPUploader(
 	conf config.SCPConfig,
 	artifactsConfig config.ArtifactsConfig,
+	limiter *BandwidthLimiter,
 ) (*scpUploader, error) {
 	u := &scpUploader{
 		artifactsConfig: artifactsConfig,
@@ -32,32 +40,211 @@ func NewSCPUploader(
 		address:         conf.Address,
 		username:        conf.Username,
 		privKeyFile:     conf.PrivateKeyFile,
+		verify:          conf.Verify,
+		overwrite:       conf.Overwrite,
+		limiter:         limiter,
 	}
 
 	return u, nil
 }
 
-func (u *scpUploader) Upload(round Round) error {
+func (u *scpUploader) Upload(ctx context.Context, round Round) UploadResults {
 	log := slog.With("op", "scpUploader.Upload")
 
+	results := make(UploadResults, len(round))
+
 	for typ, artifact := range round {
-		out, err := exec.Command("scp", "-B", "-i", u.privKeyFile, artifact.Path, fmt.Sprintf(
-			"%s@%s:%s",
-			u.username,
-			u.address,
-			u.fullUploadPath(typ, artifact.Path),
-		)).CombinedOutput()
+		start := time.Now()
+		result := u.uploadFile(ctx, typ, artifact.Path)
+		result.Duration = time.Since(start)
+		results[typ] = result
+
+		if result.Err == nil {
+			log.Debug("uploaded file via SCP", "path", artifact.Path, "skipped", result.Skipped)
+		}
+	}
+
+	return results
+}
+
+func (u *scpUploader) uploadFile(ctx context.Context, typ config.ArtifactType, path string) UploadResult {
+	result := UploadResult{RemotePath: u.fullUploadPath(typ, path)}
+
+	loc := u.artifactsConfig[typ]
+
+	if u.overwrite != config.OverwriteAlways {
+		skip, err := u.skip(ctx, typ, path, loc)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if skip {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	var sum string
+	if !loc.Raw() || u.limiter != nil {
+		d, err := u.streamFile(ctx, typ, path, loc)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Bytes, sum = d.n, d.Hex()
+	} else {
+		if err := u.copyFile(ctx, typ, path); err != nil {
+			result.Err = err
+			return result
+		}
+
+		var err error
+		if u.verify {
+			result.Bytes, sum, err = hashArtifact(path, config.Location{})
+		} else {
+			result.Bytes, err = uploadSize(path, config.Location{})
+		}
 		if err != nil {
-			log.Debug("SCP command output", "output", string(out))
-			return err
+			result.Err = err
+			return result
 		}
-		log.Debug("uploaded file via SCP", "path", artifact.Path)
+	}
+
+	if u.verify {
+		remote, err := u.remoteFile(ctx, typ, path)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if remote.SHA256 != sum {
+			result.Err = checksumMismatch(path, sum, remote.SHA256)
+		}
+	}
+
+	return result
+}
+
+// copyFile copies the file to a partial name and renames it once complete,
+// so the final path never serves a half-written file.
+func (u *scpUploader) copyFile(ctx context.Context, typ config.ArtifactType, path string) error {
+	out, err := exec.CommandContext(ctx, "scp", "-B", "-i", u.privKeyFile, path, fmt.Sprintf(
+		"%s@%s:%s",
+		u.username,
+		u.address,
+		u.partialUploadPath(typ, path),
+	)).CombinedOutput()
+	if err != nil {
+		slog.Debug("SCP command output", "output", string(out), "op", "scpUploader.copyFile")
+		return err
+	}
+
+	out, err = u.sshCommand(ctx, u.renameCommand(typ, path)).CombinedOutput()
+	if err != nil {
+		slog.Debug("SSH command output", "output", string(out), "op", "scpUploader.copyFile")
+		return err
 	}
 
 	return nil
 }
 
+// streamFile pipes the file to the remote over SSH, for compression, encryption and bandwidth limiting
+// which scp cannot do on its own. It returns the digest of the data sent.
+func (u *scpUploader) streamFile(ctx context.Context, typ config.ArtifactType, path string, loc config.Location) (*digest, error) {
+	r, err := openArtifact(path, loc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	h := newDigest()
+
+	cmd := u.sshCommand(ctx, "cat > "+shellQuote(u.partialUploadPath(typ, path))+" && "+u.renameCommand(typ, path))
+	cmd.Stdin = io.TeeReader(u.limiter.Reader(ctx, r), h)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Debug("SSH command output", "output", string(out), "op", "scpUploader.streamFile")
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (u *scpUploader) skip(ctx context.Context, typ config.ArtifactType, path string, loc config.Location) (bool, error) {
+	remote, err := u.remoteFile(ctx, typ, path)
+	if err != nil {
+		return false, err
+	}
+
+	return skipUpload(u.overwrite, remote, path, loc)
+}
+
+// remoteFile returns the size and SHA-256 of the uploaded file.
+func (u *scpUploader) remoteFile(ctx context.Context, typ config.ArtifactType, path string) (remoteFile, error) {
+	remotePath := shellQuote(u.fullUploadPath(typ, path))
+
+	out, err := u.sshCommand(ctx, "if [ -e "+remotePath+" ]; then stat -c %s "+remotePath+" && sha256sum "+remotePath+"; fi").Output()
+	if err != nil {
+		return remoteFile{}, err
+	}
+
+	return parseRemoteFile(string(out))
+}
+
+// parseRemoteFile reads the output of stat and sha256sum, an empty output means the file
+// does not exist.
+func parseRemoteFile(out string) (remoteFile, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return remoteFile{Size: -1}, nil
+	}
+	if len(fields) < 2 {
+		return remoteFile{}, fmt.Errorf("unexpected remote file info %q", out)
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return remoteFile{}, fmt.Errorf("unexpected remote file size %q", fields[0])
+	}
+
+	return remoteFile{Exists: true, Size: size, SHA256: fields[1]}, nil
+}
+
+// Check tells whether the remote accepts the SSH key.
+func (u *scpUploader) Check() error {
+	out, err := u.sshCommand(context.Background(), "true").CombinedOutput()
+	if err != nil {
+		slog.Debug("SSH command output", "output", string(out), "op", "scpUploader.Check")
+		return err
+	}
+
+	return nil
+}
+
+func (u *scpUploader) sshCommand(ctx context.Context, remoteCmd string) *exec.Cmd {
+	return exec.CommandContext(
+		ctx,
+		"ssh", "-o", "BatchMode=yes",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", int(defaultConnTimeout.Seconds())),
+		"-i", u.privKeyFile,
+		fmt.Sprintf("%s@%s", u.username, u.address),
+		remoteCmd,
+	)
+}
+
 func (u *scpUploader) fullUploadPath(typ config.ArtifactType, path string) string {
-	filename := filepath.Base(path)
-	return filepath.Join(u.basePath, u.artifactsConfig[typ].UploadPath, filename)
+	return filepath.Join(u.basePath, u.artifactsConfig[typ].UploadPath, u.artifactsConfig.RemoteName(typ, path))
+}
+
+func (u *scpUploader) partialUploadPath(typ config.ArtifactType, path string) string {
+	return filepath.Join(u.basePath, u.artifactsConfig[typ].UploadPath, partialName(u.artifactsConfig.RemoteName(typ, path)))
+}
+
+func (u *scpUploader) renameCommand(typ config.ArtifactType, path string) string {
+	return "mv -f " + shellQuote(u.partialUploadPath(typ, path)) + " " + shellQuote(u.fullUploadPath(typ, path))
+}
+
+// shellQuote quotes s for the remote POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
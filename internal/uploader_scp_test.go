@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemoteFile(t *testing.T) {
+	remote, err := parseRemoteFile("")
+	require.NoError(t, err)
+	require.Equal(t, remoteFile{Size: -1}, remote)
+
+	remote, err = parseRemoteFile("4\n9f86d081  /srv/bf2demos/file1.bf2demo.age\n")
+	require.NoError(t, err)
+	require.Equal(t, remoteFile{Exists: true, Size: 4, SHA256: "9f86d081"}, remote)
+
+	_, err = parseRemoteFile("stat: cannot stat\n")
+	require.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestInstrumentUploader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	metrics.UploadAttempts.Reset()
+	metrics.UploadFailures.Reset()
+	metrics.UploadBytes.Reset()
+
+	round := Round{
+		config.ArtifactTypeBF2Demo: {Path: "file1.bf2demo", Type: config.ArtifactTypeBF2Demo},
+		config.ArtifactTypePRDemo:  {Path: "file1.prdemo", Type: config.ArtifactTypePRDemo},
+	}
+
+	mock := NewMockUploader(ctrl)
+	mock.EXPECT().Upload(gomock.Any(), round).Return(UploadResults{
+		config.ArtifactTypeBF2Demo: {Bytes: 1024, Duration: time.Second},
+		config.ArtifactTypePRDemo:  {Err: errors.New("connection refused")},
+	})
+
+	uploader := InstrumentUploader("instrumented", "scp", mock)
+	uploader.Upload(context.Background(), round)
+
+	bf2demo := []string{"instrumented", "scp", config.ArtifactTypeBF2Demo.String()}
+	prdemo := []string{"instrumented", "scp", config.ArtifactTypePRDemo.String()}
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.UploadAttempts.WithLabelValues(bf2demo...)))
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.UploadFailures.WithLabelValues(bf2demo...)))
+	require.Equal(t, 1024.0, testutil.ToFloat64(metrics.UploadBytes.WithLabelValues(bf2demo...)))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.UploadAttempts.WithLabelValues(prdemo...)))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.UploadFailures.WithLabelValues(prdemo...)))
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.UploadBytes.WithLabelValues(prdemo...)))
+}
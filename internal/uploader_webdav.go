@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+)
+
+const defaultWebDAVRetryDelay = 5 * time.Second
+
+type webdavUploader struct {
+	artifactsConfig config.ArtifactsConfig
+	conf            config.WebDAVConfig
+	client          *http.Client
+	limiter         *BandwidthLimiter
+	retryDelay      time.Duration
+
+	// collectionsMu guards collections, the upload paths known to exist.
+	collectionsMu sync.Mutex
+	collections   map[string]bool
+}
+
+func NewWebDAVUploader(
+	conf config.WebDAVConfig,
+	artifactsConf config.ArtifactsConfig,
+	limiter *BandwidthLimiter,
+) (*webdavUploader, error) {
+	client, err := newHTTPClient(conf.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	retryDelay := conf.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultWebDAVRetryDelay
+	}
+
+	return &webdavUploader{
+		artifactsConfig: artifactsConf,
+		conf:            conf,
+		client:          client,
+		limiter:         limiter,
+		retryDelay:      retryDelay,
+		collections:     make(map[string]bool),
+	}, nil
+}
+
+func (u *webdavUploader) Upload(ctx context.Context, round Round) UploadResults {
+	log := slog.With("op", "webdavUploader.Upload")
+
+	results := make(UploadResults, len(round))
+
+	for typ, artifact := range round {
+		start := time.Now()
+		result := u.uploadFile(ctx, typ, artifact.Path)
+		result.Duration = time.Since(start)
+		results[typ] = result
+
+		if result.Err == nil {
+			log.Debug("uploaded file via WebDAV", "path", artifact.Path, "skipped", result.Skipped)
+		}
+	}
+
+	return results
+}
+
+// uploadFile PUTs the file under its partial name and moves it in place once complete.
+func (u *webdavUploader) uploadFile(ctx context.Context, typ config.ArtifactType, filename string) UploadResult {
+	uploadPath := u.artifactsConfig[typ].UploadPath
+	remoteName := u.artifactsConfig.RemoteName(typ, filename)
+
+	result := UploadResult{RemotePath: path.Join(uploadPath, remoteName)}
+
+	if u.conf.Overwrite != config.OverwriteAlways {
+		skip, err := u.skip(ctx, typ, filename)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if skip {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	if err := u.ensureCollection(ctx, uploadPath); err != nil {
+		result.Err = err
+		return result
+	}
+
+	d, err := u.put(ctx, typ, filename, path.Join(uploadPath, partialName(remoteName)))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Bytes = d.n
+
+	if err := u.move(ctx, path.Join(uploadPath, partialName(remoteName)), result.RemotePath); err != nil {
+		result.Err = err
+		return result
+	}
+
+	return result
+}
+
+// put uploads the file to name, it returns the digest of the data sent.
+func (u *webdavUploader) put(ctx context.Context, typ config.ArtifactType, filename, name string) (*digest, error) {
+	uri, err := url.JoinPath(u.conf.URL, name)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := u.artifactsConfig[typ]
+
+	var (
+		file io.ReadCloser
+		d    *digest
+	)
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	// Every attempt sends the file from the start.
+	resp, err := u.do(func() (*http.Request, error) {
+		if file != nil {
+			file.Close()
+		}
+
+		var err error
+		file, err = openArtifact(filename, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		d = newDigest()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, uri, io.TeeReader(u.limiter.Reader(ctx, file), d))
+		if err != nil {
+			return nil, err
+		}
+
+		// The size of compressed or encrypted files is not known before they are sent.
+		if loc.Raw() {
+			fi, err := os.Stat(filename)
+			if err != nil {
+				return nil, err
+			}
+			req.ContentLength = fi.Size()
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("upload failed with status: %s", resp.Status)
+	}
+
+	return d, nil
+}
+
+// move renames the uploaded file from src to dst, replacing dst.
+func (u *webdavUploader) move(ctx context.Context, src, dst string) error {
+	srcURL, err := url.JoinPath(u.conf.URL, src)
+	if err != nil {
+		return err
+	}
+
+	dstURL, err := url.JoinPath(u.conf.URL, dst)
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.do(func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "MOVE", srcURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Destination", dstURL)
+		req.Header.Set("Overwrite", "T")
+
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("move failed with status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// ensureCollection creates the collection dir and its parents with MKCOL requests,
+// once per uploader.
+func (u *webdavUploader) ensureCollection(ctx context.Context, dir string) error {
+	u.collectionsMu.Lock()
+	defer u.collectionsMu.Unlock()
+
+	var current string
+	for _, segment := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		current = path.Join(current, segment)
+		if u.collections[current] {
+			continue
+		}
+
+		if err := u.mkcol(ctx, current); err != nil {
+			return err
+		}
+
+		u.collections[current] = true
+	}
+
+	return nil
+}
+
+func (u *webdavUploader) mkcol(ctx context.Context, dir string) error {
+	// A trailing slash keeps servers from redirecting to the collection URL.
+	uri, err := url.JoinPath(u.conf.URL, dir+"/")
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.do(func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "MKCOL", uri, nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 405 Method Not Allowed means the collection exists.
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("creating collection %s failed with status: %s", dir, resp.Status)
+	}
+
+	return nil
+}
+
+// Check tells whether the WebDAV server is reachable.
+func (u *webdavUploader) Check() error {
+	return checkHTTP(u.client, u.conf.URL, u.conf.Auth)
+}
+
+func (u *webdavUploader) skip(ctx context.Context, typ config.ArtifactType, filename string) (bool, error) {
+	remote, err := u.stat(ctx, path.Join(u.artifactsConfig[typ].UploadPath, u.artifactsConfig.RemoteName(typ, filename)))
+	if err != nil {
+		return false, err
+	}
+
+	return skipUpload(u.conf.Overwrite, remote, filename, u.artifactsConfig[typ])
+}
+
+// stat asks for the uploaded file with a HEAD request, WebDAV servers do not report checksums.
+func (u *webdavUploader) stat(ctx context.Context, name string) (remoteFile, error) {
+	uri, err := url.JoinPath(u.conf.URL, name)
+	if err != nil {
+		return remoteFile{}, err
+	}
+
+	resp, err := u.do(func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, uri, nil)
+	})
+	if err != nil {
+		return remoteFile{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return remoteFile{}, nil
+	}
+
+	if resp.StatusCode >= 400 {
+		return remoteFile{}, fmt.Errorf("stat failed with status: %s", resp.Status)
+	}
+
+	return remoteFile{
+		Exists: true,
+		Size:   resp.ContentLength,
+	}, nil
+}
+
+// do sends the request built by newRequest, building and sending it again when it
+// fails with a network error, 429 or 5xx, up to the configured number of retries.
+// The response of the last attempt is returned whatever its status.
+func (u *webdavUploader) do(newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		setAuth(req, u.conf.Auth)
+
+		resp, err := u.client.Do(req)
+		if err == nil {
+			retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+			if !retry || attempt >= u.conf.Retries {
+				return resp, nil
+			}
+
+			resp.Body.Close()
+			err = fmt.Errorf("%s failed with status: %s", req.Method, resp.Status)
+		} else if attempt >= u.conf.Retries {
+			return nil, err
+		}
+
+		slog.Warn("WebDAV request failed, retrying", "attempt", attempt+1, "err", err, "op", "webdavUploader.do")
+
+		if err := sleepContext(req.Context(), u.retryDelay); err != nil {
+			return nil, err
+		}
+	}
+}
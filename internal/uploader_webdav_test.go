@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+// newWebDAVServer serves dir over WebDAV, failing the PUT requests listed in failPuts.
+func newWebDAVServer(t *testing.T, dir string, failPuts map[int]bool) *httptest.Server {
+	handler := &webdav.Handler{
+		FileSystem: webdav.Dir(dir),
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	var (
+		mu   sync.Mutex
+		puts int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			mu.Lock()
+			puts++
+			fail := failPuts[puts]
+			mu.Unlock()
+
+			if fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestWebDAVUploader(t *testing.T) {
+	dir := t.TempDir()
+
+	bf2demo := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(bf2demo, []byte("bf2demo"), 0644))
+
+	summary := filepath.Join(t.TempDir(), "file1.json")
+	require.NoError(t, os.WriteFile(summary, []byte("summary"), 0644))
+
+	srv := newWebDAVServer(t, dir, map[int]bool{1: true})
+
+	uploader, err := NewWebDAVUploader(config.WebDAVConfig{
+		URL:        srv.URL + "/remote.php/dav",
+		Auth:       config.HTTPSAuth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}},
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "rounds/bf2demos", Compress: config.CompressionGzip},
+		config.ArtifactTypeSummary: {UploadPath: "rounds/summaries"},
+	}, nil)
+	require.NoError(t, err)
+
+	// The server is rooted at dir, the URL prefix is part of the collection path.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "remote.php", "dav"), 0755))
+
+	round := Round{
+		config.ArtifactTypeBF2Demo: {Path: bf2demo, Type: config.ArtifactTypeBF2Demo},
+		config.ArtifactTypeSummary: {Path: summary, Type: config.ArtifactTypeSummary},
+	}
+
+	results := uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+	require.Equal(t, "rounds/bf2demos/file1.bf2demo.gz", results[config.ArtifactTypeBF2Demo].RemotePath)
+
+	_, expected, err := hashArtifact(bf2demo, config.Location{Compress: config.CompressionGzip})
+	require.NoError(t, err)
+	_, actual, err := hashArtifact(filepath.Join(dir, "remote.php", "dav", "rounds", "bf2demos", "file1.bf2demo.gz"), config.Location{})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	content, err := os.ReadFile(filepath.Join(dir, "remote.php", "dav", "rounds", "summaries", "file1.json"))
+	require.NoError(t, err)
+	require.Equal(t, "summary", string(content))
+
+	partials, err := filepath.Glob(filepath.Join(dir, "remote.php", "dav", "rounds", "*", ".*.partial"))
+	require.NoError(t, err)
+	require.Empty(t, partials)
+}
+
+func TestWebDAVUploaderErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	tests := []struct {
+		name string
+		conf config.WebDAVConfig
+		err  string
+	}{
+		{
+			name: "out of retries",
+			conf: config.WebDAVConfig{
+				Auth:       config.HTTPSAuth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}},
+				Retries:    1,
+				RetryDelay: time.Millisecond,
+			},
+			err: "503 Service Unavailable",
+		},
+		{
+			name: "unauthorized",
+			conf: config.WebDAVConfig{Auth: config.HTTPSAuth{Bearer: "token"}},
+			err:  "401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newWebDAVServer(t, t.TempDir(), map[int]bool{1: true, 2: true})
+
+			tt.conf.URL = srv.URL
+			uploader, err := NewWebDAVUploader(tt.conf, config.ArtifactsConfig{
+				config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+			}, nil)
+			require.NoError(t, err)
+
+			require.ErrorContains(t, uploader.Upload(context.Background(), round).Err(round), tt.err)
+		})
+	}
+}
+
+func TestWebDAVUploaderOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bf2demos"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bf2demos", "file1.bf2demo"), []byte("curated"), 0644))
+
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	srv := newWebDAVServer(t, dir, nil)
+
+	uploader, err := NewWebDAVUploader(config.WebDAVConfig{
+		URL:       srv.URL,
+		Auth:      config.HTTPSAuth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}},
+		Overwrite: config.OverwriteIfDifferent,
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+	}, nil)
+	require.NoError(t, err)
+
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+
+	results := uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+	require.False(t, results[config.ArtifactTypeBF2Demo].Skipped)
+
+	results = uploader.Upload(context.Background(), round)
+	require.NoError(t, results.Err(round))
+	require.True(t, results[config.ArtifactTypeBF2Demo].Skipped)
+
+	content, err := os.ReadFile(filepath.Join(dir, "bf2demos", "file1.bf2demo"))
+	require.NoError(t, err)
+	require.Equal(t, "test", string(content))
+}
+
+func TestWebDAVUploaderCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file1.bf2demo")
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	uploader, err := NewWebDAVUploader(config.WebDAVConfig{
+		URL:        srv.URL,
+		Overwrite:  config.OverwriteIfDifferent,
+		Retries:    1,
+		RetryDelay: time.Hour,
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {UploadPath: "bf2demos"},
+	}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The overwrite check waits for its retry on the context of the upload.
+	round := Round{config.ArtifactTypeBF2Demo: {Path: path, Type: config.ArtifactTypeBF2Demo}}
+	require.ErrorIs(t, uploader.Upload(ctx, round).Err(round), context.DeadlineExceeded)
+}
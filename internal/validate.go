@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+)
+
+// WithValidation checks every artifact before upload, corrupt ones are moved
+// to quarantinePath/<type> instead of being uploaded.
+func WithValidation(quarantinePath string) HandlerOption {
+	return func(h *Handler) {
+		h.quarantinePath = quarantinePath
+	}
+}
+
+func validateArtifact(artifact Artifact) Artifact {
+	artifact.Status = ArtifactValid
+	artifact.Problem = ""
+
+	fi, err := os.Stat(artifact.Path)
+	if err != nil {
+		artifact.Status = ArtifactCorrupt
+		artifact.Problem = err.Error()
+		return artifact
+	}
+
+	if fi.Size() == 0 {
+		artifact.Status = ArtifactCorrupt
+		artifact.Problem = "empty file"
+		return artifact
+	}
+
+	switch artifact.Type {
+	case config.ArtifactTypePRDemo:
+		demo, err := summary.ReadDemo(artifact.Path)
+		if err != nil {
+			artifact.Status = ArtifactCorrupt
+			artifact.Problem = fmt.Sprintf("cannot read PR demo: %s", err)
+		} else if demo.Truncated {
+			artifact.Status = ArtifactTruncated
+			artifact.Problem = "PR demo is cut off"
+		} else if !demo.Ended {
+			artifact.Status = ArtifactTruncated
+			artifact.Problem = "PR demo has no end of round"
+		}
+	case config.ArtifactTypeSummary:
+		if err := summary.Validate(artifact.Path); err != nil {
+			artifact.Status = ArtifactCorrupt
+			artifact.Problem = fmt.Sprintf("invalid summary: %s", err)
+		}
+	}
+
+	return artifact
+}
+
+// validateRound validates all artifacts of the round and quarantines the corrupt ones.
+func (h *Handler) validateRound(round Round) Round {
+	log := slog.With("op", "Handler.validateRound")
+
+	validated := make(Round, len(round))
+	invalid := make(Round)
+
+	for typ, artifact := range round {
+		artifact = validateArtifact(artifact)
+		validated[typ] = artifact
+
+		if artifact.Status == ArtifactValid {
+			continue
+		}
+
+		log.Warn("invalid artifact", "path", artifact.Path, "status", artifact.Status, "problem", artifact.Problem)
+		invalid[typ] = artifact
+
+		if artifact.Status == ArtifactCorrupt {
+			newPath := filepath.Join(h.quarantinePath, typ.String(), filepath.Base(artifact.Path))
+			if err := move(artifact.Path, newPath); err != nil {
+				log.Error("failed to quarantine file", "src", artifact.Path, "dst", newPath, "err", err)
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		h.alert(Alert{Kind: AlertInvalidArtifacts, Round: invalid})
+	}
+
+	return validated
+}
@@ -4,16 +4,43 @@ import (
 	"context"
 	"log/slog"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+// watcherHeartbeatInterval is how often an idle watch loop records that it still runs.
+const watcherHeartbeatInterval = 10 * time.Second
+
 type fileHandler interface {
 	OnFileCreate(path string)
 }
 
+type WatcherState int32
+
+const (
+	WatcherStarting WatcherState = iota
+	WatcherRunning
+	WatcherStopped
+)
+
+func (s WatcherState) String() string {
+	switch s {
+	case WatcherRunning:
+		return "running"
+	case WatcherStopped:
+		return "stopped"
+	default:
+		return "starting"
+	}
+}
+
 type Watcher struct {
 	handlers map[string]fileHandler
+	state    atomic.Int32
+	// heartbeat is the time in Unix nanoseconds the watch loop last waited for events.
+	heartbeat atomic.Int64
 }
 
 func NewWatcher() *Watcher {
@@ -29,6 +56,22 @@ func (w *Watcher) Register(paths []string, handler fileHandler) {
 	}
 }
 
+// State tells whether the watch loop has started and is still running.
+func (w *Watcher) State() WatcherState {
+	return WatcherState(w.state.Load())
+}
+
+// Heartbeat returns when the watch loop last waited for events, zero before it started.
+// Files are handled in the loop, so it lags behind while a round is uploaded.
+func (w *Watcher) Heartbeat() time.Time {
+	nanos := w.heartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
+
 func (w *Watcher) Watch(ctx context.Context) error {
 	log := slog.With("op", "Watcher.Watch")
 
@@ -39,6 +82,8 @@ func (w *Watcher) Watch(ctx context.Context) error {
 
 	defer watcher.Close()
 
+	defer w.state.Store(int32(WatcherStopped))
+
 	for path := range w.handlers {
 		log.Debug("Adding path to watcher", "path", path)
 		if err := watcher.Add(path); err != nil {
@@ -46,10 +91,18 @@ func (w *Watcher) Watch(ctx context.Context) error {
 		}
 	}
 
+	w.state.Store(int32(WatcherRunning))
+
+	ticker := time.NewTicker(watcherHeartbeatInterval)
+	defer ticker.Stop()
+
 	for {
+		w.heartbeat.Store(time.Now().UnixNano())
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-ticker.C:
 		case event := <-watcher.Events:
 			if event.Op.Has(fsnotify.Create) {
 				dir := filepath.Dir(event.Name)
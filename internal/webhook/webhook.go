@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/emilekm/artifacts-mover/internal/summary"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body,
+	// prefixed with "sha256=", when a secret is configured.
+	SignatureHeader = "X-Signature-256"
+
+	defaultTimeout    = 10 * time.Second
+	defaultRetryDelay = 5 * time.Second
+)
+
+// Payload is the JSON document POSTed for every archived round.
+type Payload struct {
+	// Server is the name of the server from the config.
+	Server string `json:"server"`
+	// Types lists the artifact types that were uploaded for the round.
+	Types []string `json:"types"`
+	// URLs maps artifact type to its public download URL.
+	URLs map[string]string `json:"urls"`
+	// Incomplete is true when an artifact of the round is truncated or corrupt.
+	Incomplete bool `json:"incomplete"`
+	// Problems maps artifact type to the validation problem of the artifact.
+	// Corrupt artifacts are not uploaded and have no URL.
+	Problems map[string]string `json:"problems,omitempty"`
+	// Summary is present when the round contains a summary artifact or a PR demo.
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// AlertPayload is the JSON document POSTed for operational alerts.
+type AlertPayload struct {
+	Server string `json:"server"`
+	// Kind is one of uploadFailed, roundTimeout, backlog or invalidArtifacts.
+	Kind string `json:"kind"`
+	// Files lists the files of the affected round.
+	Files []string `json:"files,omitempty"`
+	// Problems maps file name to the validation problem, set for invalidArtifacts.
+	Problems map[string]string `json:"problems,omitempty"`
+	// Error is the upload error, set for uploadFailed.
+	Error string `json:"error,omitempty"`
+	// MissingTypes lists the artifact types the round did not receive, set for roundTimeout.
+	MissingTypes []string `json:"missingTypes,omitempty"`
+	// Backlog is the number of files waiting in the failed upload directory, set for backlog.
+	Backlog int `json:"backlog,omitempty"`
+}
+
+type Summary struct {
+	Map       string    `json:"map"`
+	Mode      string    `json:"mode"`
+	Layer     int       `json:"layer"`
+	Team1     Team      `json:"team1"`
+	Team2     Team      `json:"team2"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Players   []Player  `json:"players"`
+}
+
+type Team struct {
+	Name    string `json:"name"`
+	Tickets int    `json:"tickets"`
+}
+
+type Player struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+type Client struct {
+	client     *http.Client
+	server     string
+	conf       config.Webhook
+	artifacts  config.ArtifactsConfig
+	retryDelay time.Duration
+}
+
+func New(server string, conf config.Webhook, artifacts config.ArtifactsConfig) *Client {
+	timeout := conf.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	retryDelay := conf.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultRetryDelay
+	}
+
+	return &Client{
+		client:     &http.Client{Timeout: timeout},
+		server:     server,
+		conf:       conf,
+		artifacts:  artifacts,
+		retryDelay: retryDelay,
+	}
+}
+
+// Send posts the round once, retries are left to the notifier options of the server.
+// A rejected request is returned as an internal.PermanentError.
+func (c *Client) Send(ctx context.Context, round internal.Round) error {
+	payload, err := c.buildPayload(round)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	retry, err := c.post(ctx, body)
+	if err != nil && !retry {
+		return internal.Permanent(err)
+	}
+
+	return err
+}
+
+func (c *Client) Alert(ctx context.Context, alert internal.Alert) error {
+	payload := &AlertPayload{
+		Server:  c.server,
+		Kind:    string(alert.Kind),
+		Backlog: alert.Backlog,
+	}
+
+	if alert.Err != nil {
+		payload.Error = alert.Err.Error()
+	}
+
+	for _, artifact := range alert.Round {
+		payload.Files = append(payload.Files, filepath.Base(artifact.Path))
+
+		if artifact.Problem != "" {
+			if payload.Problems == nil {
+				payload.Problems = make(map[string]string)
+			}
+			payload.Problems[filepath.Base(artifact.Path)] = fmt.Sprintf("%s: %s", artifact.Status, artifact.Problem)
+		}
+	}
+	slices.Sort(payload.Files)
+
+	for _, typ := range alert.MissingTypes {
+		payload.MissingTypes = append(payload.MissingTypes, typ.String())
+	}
+
+	return c.postWithRetries(ctx, payload)
+}
+
+// postWithRetries posts the payload, repeating failures worth retrying up to the
+// configured number of retries. Only alerts use it, they have no other retry layer.
+func (c *Client) postWithRetries(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	log := slog.With("op", "webhook.Client.postWithRetries", "server", c.server)
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= c.conf.Retries {
+			return err
+		}
+
+		log.Warn("webhook request failed, retrying", "attempt", attempt+1, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// post sends the body once and reports whether a failure is worth retrying.
+func (c *Client) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range c.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if c.conf.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign([]byte(c.conf.Secret), body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("webhook failed with status: %s", resp.Status)
+	}
+
+	return false, nil
+}
+
+func (c *Client) buildPayload(round internal.Round) (*Payload, error) {
+	payload := &Payload{
+		Server: c.server,
+		Types:  make([]string, 0, len(round)),
+		URLs:   make(map[string]string),
+	}
+
+	for typ, artifact := range round {
+		if artifact.Problem != "" {
+			if payload.Problems == nil {
+				payload.Problems = make(map[string]string)
+			}
+			payload.Incomplete = true
+			payload.Problems[typ.String()] = fmt.Sprintf("%s: %s", artifact.Status, artifact.Problem)
+		}
+
+		if artifact.Status == internal.ArtifactCorrupt {
+			continue
+		}
+
+		payload.Types = append(payload.Types, typ.String())
+
+		if artifact.URL != "" {
+			payload.URLs[typ.String()] = artifact.URL
+		} else if baseURL, ok := c.conf.URLS[typ.String()]; ok {
+			payload.URLs[typ.String()] = baseURL + "/" + c.artifacts.RemoteName(typ, artifact.Path)
+		}
+	}
+
+	slices.Sort(payload.Types)
+
+	usable := round.Usable()
+
+	s, _, err := summary.Resolve(usable[config.ArtifactTypeSummary].Path, usable[config.ArtifactTypePRDemo].Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return payload, nil
+	}
+
+	payload.Summary = &Summary{
+		Map:       s.MapName,
+		Mode:      s.MapMode,
+		Layer:     s.MapLayer,
+		Team1:     Team{Name: s.Team1Name, Tickets: s.Team1Tickets},
+		Team2:     Team{Name: s.Team2Name, Tickets: s.Team2Tickets},
+		StartTime: time.Unix(s.StartTime, 0).UTC(),
+		EndTime:   time.Unix(s.EndTime, 0).UTC(),
+		Players:   make([]Player, 0, len(s.Players)),
+	}
+
+	for _, p := range s.Players {
+		payload.Summary.Players = append(payload.Summary.Players, Player{Name: p.Name, Score: p.Score})
+	}
+
+	return payload, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emilekm/artifacts-mover/internal"
+	"github.com/emilekm/artifacts-mover/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSend(t *testing.T) {
+	dir := t.TempDir()
+	summaryPath := filepath.Join(dir, "round1.json")
+	require.NoError(t, os.WriteFile(summaryPath, []byte(`{
+		"MapName": "muttrah_city_2",
+		"MapMode": "gpm_cq",
+		"MapLayer": 64,
+		"Team1Name": "MEC",
+		"Team2Name": "USA",
+		"Team1Tickets": 120,
+		"Team2Tickets": 0,
+		"StartTime": 1700000000,
+		"EndTime": 1700003600,
+		"Players": [{"Name": "player1", "Score": 42}]
+	}`), 0644))
+
+	round := internal.Round{
+		config.ArtifactTypeBF2Demo: {Path: filepath.Join(dir, "round1.bf2demo"), Type: config.ArtifactTypeBF2Demo},
+		config.ArtifactTypeSummary: {Path: summaryPath, Type: config.ArtifactTypeSummary},
+	}
+
+	type request struct {
+		body      []byte
+		signature string
+	}
+
+	requests := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{body: body, signature: r.Header.Get(SignatureHeader)}
+	}))
+	defer srv.Close()
+
+	client := New("my-server", config.Webhook{
+		URL:    srv.URL,
+		Secret: "secret",
+		URLS:   map[string]string{"bf2demo": "https://example.com/bf2demos"},
+	}, config.ArtifactsConfig{
+		config.ArtifactTypeBF2Demo: {Compress: config.CompressionGzip},
+	})
+
+	require.NoError(t, client.Send(context.Background(), round))
+
+	req := <-requests
+	require.Equal(t, "sha256="+Sign([]byte("secret"), req.body), req.signature)
+
+	var received Payload
+	require.NoError(t, json.Unmarshal(req.body, &received))
+
+	require.Equal(t, "my-server", received.Server)
+	require.Equal(t, []string{"bf2demo", "summary"}, received.Types)
+	require.Equal(t, map[string]string{"bf2demo": "https://example.com/bf2demos/round1.bf2demo.gz"}, received.URLs)
+	require.NotNil(t, received.Summary)
+	require.Equal(t, "muttrah_city_2", received.Summary.Map)
+	require.Equal(t, Team{Name: "MEC", Tickets: 120}, received.Summary.Team1)
+	require.Equal(t, time.Unix(1700003600, 0).UTC(), received.Summary.EndTime)
+	require.Equal(t, []Player{{Name: "player1", Score: 42}}, received.Summary.Players)
+}
+
+func TestClientSendSingleAttempt(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		permanent bool
+	}{
+		{name: "server error", status: http.StatusServiceUnavailable},
+		{name: "client error", status: http.StatusBadRequest, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			// Retries of round notifications are left to internal.NotifierSpec.
+			client := New("my-server", config.Webhook{
+				URL:        srv.URL,
+				Retries:    3,
+				RetryDelay: time.Millisecond,
+			}, nil)
+
+			err := client.Send(context.Background(), internal.Round{})
+			require.Error(t, err)
+			require.Equal(t, int32(1), attempts.Load())
+
+			var permanent *internal.PermanentError
+			require.Equal(t, tt.permanent, errors.As(err, &permanent))
+		})
+	}
+}
+
+func TestClientAlertRetries(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client := New("my-server", config.Webhook{
+		URL:        srv.URL,
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+	}, nil)
+
+	require.NoError(t, client.Alert(context.Background(), internal.Alert{Kind: internal.AlertBacklog, Backlog: 3}))
+	require.Equal(t, int32(2), attempts.Load())
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/emilekm/artifacts-mover/internal"
 	"github.com/emilekm/artifacts-mover/internal/config"
 	"github.com/emilekm/artifacts-mover/internal/discord"
+	"github.com/emilekm/artifacts-mover/internal/webhook"
 )
 
 const (
@@ -26,6 +29,13 @@ var configPath = flag.String("config", "config.yaml", "path to config file")
 func main() {
 	flag.Parse()
 
+	if flag.Arg(0) == "decrypt" {
+		if err := runDecrypt(flag.Args()[1:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	if err := run(ctx, *configPath); err != nil {
 		log.Fatalf("Error: %v", err)
@@ -57,18 +67,66 @@ func run(ctx context.Context, confPath string) error {
 
 	handlers := make([]*internal.Handler, 0)
 
+	globalLimiter := internal.NewBandwidthLimiter(conf.BandwidthLimit, nil)
+
+	var checkInterval, stallTimeout time.Duration
+	if conf.Admin != nil {
+		checkInterval = conf.Admin.CheckInterval
+		stallTimeout = conf.Admin.StallTimeout
+	}
+
+	health := internal.NewHealth(w, checkInterval, stallTimeout)
+	discordRequired := false
+
 	for name, server := range conf.Servers {
 		svFailedPath := filepath.Join(conf.FailedUploadPath, name)
 		if err := os.MkdirAll(svFailedPath, 0755); err != nil {
 			return err
 		}
 
-		var uploader internal.Uploader
+		uploadArtifacts := server.Artifacts
+		if server.Manifest != nil {
+			uploadArtifacts = maps.Clone(server.Artifacts)
+			uploadArtifacts[config.ArtifactTypeManifest] = config.Location{
+				UploadPath: server.Manifest.UploadPath,
+				Encrypt:    server.Upload.Encrypt,
+			}
+		}
+
+		limiter := internal.NewBandwidthLimiter(server.BandwidthLimit, globalLimiter)
+
+		var (
+			uploader    internal.Uploader
+			destination string
+		)
 
 		if server.Upload.HTTPS != nil {
-			uploader = internal.NewHTTPSUploader(*server.Upload.HTTPS, server.Artifacts)
+			destination = "https"
+			uploader, err = internal.NewHTTPSUploader(*server.Upload.HTTPS, uploadArtifacts, limiter)
+			if err != nil {
+				return err
+			}
+		} else if server.Upload.WebDAV != nil {
+			destination = "webdav"
+			uploader, err = internal.NewWebDAVUploader(*server.Upload.WebDAV, uploadArtifacts, limiter)
+			if err != nil {
+				return err
+			}
+		} else if server.Upload.FTP != nil {
+			destination = "ftp"
+			uploader, err = internal.NewFTPUploader(*server.Upload.FTP, uploadArtifacts, limiter)
+			if err != nil {
+				return err
+			}
 		} else if server.Upload.SCP != nil {
-			uploader, err = internal.NewSCPUploader(*server.Upload.SCP, server.Artifacts)
+			destination = "scp"
+			uploader, err = internal.NewSCPUploader(*server.Upload.SCP, uploadArtifacts, limiter)
+			if err != nil {
+				return err
+			}
+		} else if server.Upload.Local != nil {
+			destination = "local"
+			uploader, err = internal.NewLocalUploader(*server.Upload.Local, uploadArtifacts, limiter)
 			if err != nil {
 				return err
 			}
@@ -76,9 +134,36 @@ func run(ctx context.Context, confPath string) error {
 			return errors.New("no upload method configured")
 		}
 
-		discordClient, err := discord.New(bot.Session(), server.Discord.ChannelID, server.Discord.URLS)
-		if err != nil {
-			return err
+		checker, ok := uploader.(internal.Checker)
+		if !ok {
+			return fmt.Errorf("server %s: %s uploader cannot be checked", name, destination)
+		}
+
+		uploader = internal.InstrumentUploader(name, destination, uploader)
+
+		notifierConfs := server.Notifiers
+		if server.Discord.ChannelID != "" {
+			notifierConfs = append(notifierConfs, config.Notifier{Discord: &server.Discord})
+		}
+		if server.Webhook != nil {
+			notifierConfs = append(notifierConfs, config.Notifier{Webhook: server.Webhook})
+		}
+
+		for _, notifierConf := range notifierConfs {
+			if notifierConf.Discord != nil {
+				discordRequired = true
+			}
+		}
+
+		notifiers := make([]internal.NotifierSpec, 0, len(notifierConfs))
+
+		for i, notifierConf := range notifierConfs {
+			spec, err := newNotifier(name, i, notifierConf, server.Artifacts, bot.Session())
+			if err != nil {
+				return err
+			}
+
+			notifiers = append(notifiers, spec)
 		}
 
 		roundTimeout := server.RoundTimeout
@@ -86,7 +171,30 @@ func run(ctx context.Context, confPath string) error {
 			roundTimeout = defaultRoundTimer
 		}
 
-		handler, err := internal.NewHandler(uploader, discordClient, server.Artifacts, roundTimeout, svFailedPath)
+		handlerOpts := []internal.HandlerOption{internal.WithServer(name)}
+
+		if server.Alerts != nil {
+			alerters := make([]internal.Alerter, 0, 2)
+			if server.Alerts.Discord != nil {
+				discordRequired = true
+				alerters = append(alerters, discord.NewAlerter(bot.Session(), server.Alerts.Discord.ChannelID, name))
+			}
+			if server.Alerts.Webhook != nil {
+				alerters = append(alerters, webhook.New(name, *server.Alerts.Webhook, server.Artifacts))
+			}
+
+			handlerOpts = append(handlerOpts, internal.WithAlerters(server.Alerts.BacklogThreshold, alerters...))
+		}
+
+		if server.Manifest != nil {
+			handlerOpts = append(handlerOpts, internal.WithManifest(name, filepath.Join(svFailedPath, "manifests")))
+		}
+
+		if server.Validate {
+			handlerOpts = append(handlerOpts, internal.WithValidation(filepath.Join(svFailedPath, "quarantine")))
+		}
+
+		handler, err := internal.NewHandler(uploader, notifiers, server.Artifacts, roundTimeout, svFailedPath, handlerOpts...)
 		if err != nil {
 			return err
 		}
@@ -94,16 +202,45 @@ func run(ctx context.Context, confPath string) error {
 		handlers = append(handlers, handler)
 
 		paths := make([]string, 0, len(server.Artifacts))
+		dirs := []string{svFailedPath}
 
 		for _, loc := range server.Artifacts {
 			paths = append(paths, loc.Location)
+			dirs = append(dirs, loc.Location)
+			if loc.MovePath != nil {
+				dirs = append(dirs, *loc.MovePath)
+			}
 		}
 
+		health.AddServer(name, checker, dirs)
+
 		defer handler.Close()
 
 		w.Register(paths, handler)
 	}
 
+	if discordRequired {
+		health.RequireDiscord(func() bool {
+			s := bot.Session()
+			s.RLock()
+			defer s.RUnlock()
+			return s.DataReady
+		})
+	}
+
+	if conf.Admin != nil {
+		admin, err := serveAdmin(*conf.Admin, health)
+		if err != nil {
+			return fmt.Errorf("admin listener: %w", err)
+		}
+		defer admin.Close()
+
+		healthCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go health.Run(healthCtx)
+	}
+
 	blockCh := make(chan struct{})
 
 	bot.Session().AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
@@ -131,3 +268,37 @@ func run(ctx context.Context, confPath string) error {
 
 	return w.Watch(ctx)
 }
+
+func newNotifier(server string, index int, conf config.Notifier, artifacts config.ArtifactsConfig, session *discordgo.Session) (internal.NotifierSpec, error) {
+	spec := internal.NotifierSpec{
+		Timeout:    conf.Timeout,
+		Retries:    conf.Retries,
+		RetryDelay: conf.RetryDelay,
+	}
+
+	switch {
+	case conf.Discord != nil:
+		client, err := discord.New(session, *conf.Discord, artifacts)
+		if err != nil {
+			return spec, err
+		}
+
+		spec.Name = fmt.Sprintf("discord[%d]", index)
+		spec.Notifier = client
+	case conf.Webhook != nil:
+		// The retries of the webhook apply unless the notifier sets its own.
+		if spec.Retries == 0 {
+			spec.Retries = conf.Webhook.Retries
+		}
+		if spec.RetryDelay == 0 {
+			spec.RetryDelay = conf.Webhook.RetryDelay
+		}
+
+		spec.Name = fmt.Sprintf("webhook[%d]", index)
+		spec.Notifier = webhook.New(server, *conf.Webhook, artifacts)
+	default:
+		return spec, fmt.Errorf("server %s: notifier %d has no backend configured", server, index)
+	}
+
+	return spec, nil
+}